@@ -0,0 +1,147 @@
+package main
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/natrim/nrb/lib"
+)
+
+// analyzeHTML is treemap page of bundle, it loads tree from /analyze.json
+//
+//go:embed analyze.html
+var analyzeHTML []byte
+
+// analyzeNode is directory or file in bundle treemap
+type analyzeNode struct {
+	Name     string         `json:"name"`
+	Bytes    int64          `json:"bytes"`
+	Children []*analyzeNode `json:"children,omitempty"`
+}
+
+// analyzeReport is data of treemap, outputs with bytes of their inputs in output and inputs with their source size
+type analyzeReport struct {
+	Outputs *analyzeNode `json:"outputs"`
+	Inputs  *analyzeNode `json:"inputs"`
+}
+
+// analyzeCommand handles 'analyze' command with its own flags after it
+func analyzeCommand(args []string) error {
+	flags := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	buildFlag := false
+	textFlag := false
+	verboseFlag := false
+	flags.BoolVar(&buildFlag, "build", buildFlag, "build the app even if 'build-meta.json' exists in output dir")
+	flags.BoolVar(&textFlag, "text", textFlag, "only print analysis to terminal, do not serve treemap")
+	flags.BoolVar(&verboseFlag, "verbose", verboseFlag, "print import chains of every input in analysis")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unknown argument '%s' of analyze command", flags.Arg(0))
+	}
+
+	metafileJSON, metafile, err := loadMetafile(buildFlag)
+	if err != nil {
+		return err
+	}
+
+	lib.Print(api.AnalyzeMetafile(string(metafileJSON), api.AnalyzeMetafileOptions{
+		Color:   cliState.UseColor,
+		Verbose: verboseFlag,
+	}))
+
+	if textFlag {
+		return nil
+	}
+
+	report, err := json.Marshal(analyzeTree(metafile, config.OutputDir))
+	if err != nil {
+		return err
+	}
+
+	SetupWebServer()
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			error404(w, true)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(analyzeHTML)
+	})
+	http.HandleFunc("/analyze.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(report)
+	})
+
+	return listenAndServe()
+}
+
+// analyzeTree makes treemap trees of metafile outputs and inputs, paths are split by directories, biggest nodes go first
+func analyzeTree(metafile Metadata, outputRoot string) analyzeReport {
+	outputs := &analyzeNode{Name: "outputs"}
+	for out, m := range metafile.Outputs {
+		if filepath.Ext(out) == ".map" {
+			continue
+		}
+		node := &analyzeNode{Name: strings.TrimPrefix(outputPath(out, outputRoot), "/")}
+		for input, i := range m.Inputs {
+			node.add(strings.Split(input, "/"), int64(i.BytesInOutput))
+		}
+		// rest of output is esbuild runtime and glue code, copied assets have no bytes from inputs at all
+		if other := int64(m.Bytes) - node.Bytes; other > 0 && node.Bytes > 0 {
+			node.add([]string{"(other)"}, other)
+		} else if node.Bytes == 0 {
+			node.Bytes = int64(m.Bytes)
+		}
+		outputs.Bytes += node.Bytes
+		outputs.Children = append(outputs.Children, node)
+	}
+
+	inputs := &analyzeNode{Name: "inputs"}
+	for input, m := range metafile.Inputs {
+		inputs.add(strings.Split(input, "/"), int64(m.Bytes))
+	}
+
+	outputs.sort()
+	inputs.sort()
+	return analyzeReport{Outputs: outputs, Inputs: inputs}
+}
+
+// add adds bytes of file at path parts under node
+func (n *analyzeNode) add(parts []string, bytes int64) {
+	n.Bytes += bytes
+	if len(parts) == 0 {
+		return
+	}
+	for _, child := range n.Children {
+		if child.Name == parts[0] {
+			child.add(parts[1:], bytes)
+			return
+		}
+	}
+	child := &analyzeNode{Name: parts[0]}
+	n.Children = append(n.Children, child)
+	child.add(parts[1:], bytes)
+}
+
+func (n *analyzeNode) sort() {
+	slices.SortFunc(n.Children, func(a, b *analyzeNode) int {
+		if c := cmp.Compare(b.Bytes, a.Bytes); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, child := range n.Children {
+		child.sort()
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/natrim/nrb/lib"
+)
+
+func TestAnalyzeTreeGroupsInputsByDirectories(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+
+	dir := t.TempDir()
+	rel := metafilePath(t, dir)
+
+	var metafile Metadata
+	err := json.Unmarshal(fmt.Appendf(nil, `{
+		"inputs": {
+			"src/index.tsx": {"bytes": 100},
+			"node_modules/react/index.js": {"bytes": 300},
+			"node_modules/react/cjs/react.js": {"bytes": 5000}
+		},
+		"outputs": {
+			"%[1]s/build/assets/index.js": {"bytes": 2500, "inputs": {
+				"src/index.tsx": {"bytesInOutput": 80},
+				"node_modules/react/index.js": {"bytesInOutput": 20},
+				"node_modules/react/cjs/react.js": {"bytesInOutput": 2000}
+			}},
+			"%[1]s/build/assets/index.js.map": {"bytes": 9000},
+			"%[1]s/build/assets/logo.png": {"bytes": 700}
+		}
+	}`, rel), &metafile)
+	if err != nil {
+		t.Fatalf("failed to parse metafile: %v", err)
+	}
+
+	report := analyzeTree(metafile, filepath.Join(dir, "build"))
+
+	outputs := report.Outputs
+	if outputs.Bytes != 3200 || len(outputs.Children) != 2 {
+		t.Fatalf("outputs = %+v, want js and png without source map", outputs)
+	}
+	js := outputs.Children[0]
+	if js.Name != "assets/index.js" || js.Bytes != 2500 || js.Children[0].Name != "node_modules" || js.Children[0].Bytes != 2020 {
+		t.Fatalf("js output = %+v, want node_modules first by bytes in output", js)
+	}
+	if other := js.Children[1]; other.Name != "(other)" || other.Bytes != 400 {
+		t.Fatalf("js output rest = %+v, want 400 bytes not from inputs", other)
+	}
+	if png := outputs.Children[1]; png.Name != "assets/logo.png" || png.Bytes != 700 || png.Children != nil {
+		t.Fatalf("png output = %+v, want leaf with output bytes", png)
+	}
+
+	react := report.Inputs.Children[0].Children[0]
+	if report.Inputs.Bytes != 5400 || react.Name != "react" || react.Bytes != 5300 || react.Children[0].Name != "cjs" {
+		t.Fatalf("inputs = %+v, want react dir with cjs first", report.Inputs)
+	}
+}
+
+func TestAnalyzeCommandWorksWhenBuildFailsBudgets(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+
+	baseDir = t.TempDir()
+	writePackageJSON(t, baseDir, `{"name": "app", "nrb": {"budgets": {"entry": "10b"}}}`)
+	writeFile(t, filepath.Join(baseDir, "tsconfig.json"), `{"compilerOptions": {"target": "es2022"}}`)
+	for _, dir := range []string{"src", "public"} {
+		if err := os.MkdirAll(filepath.Join(baseDir, dir), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	writeFile(t, filepath.Join(baseDir, "src", "index.tsx"), `console.log("this entry is over budget")`)
+	writeFile(t, filepath.Join(baseDir, "public", "index.html"), "<html><head></head><body></body></html>")
+	if err := refreshRuntimeConfig(true); err != nil {
+		t.Fatalf("refreshRuntimeConfig() returned error: %v", err)
+	}
+
+	if err := build(); !errors.Is(err, errBuildChecks) {
+		t.Fatalf("build() = %v, want failed budget", err)
+	}
+	if err := analyzeCommand([]string{"-build", "-text"}); err != nil {
+		t.Fatalf("analyzeCommand() returned error: %v", err)
+	}
+	if !lib.FileExists(filepath.Join(config.OutputDir, "build-meta.json")) {
+		t.Fatal("analyze build did not write build-meta.json")
+	}
+}
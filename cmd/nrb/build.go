@@ -4,9 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,12 +15,19 @@ import (
 	"github.com/natrim/nrb/lib"
 )
 
+// errBuildChecks marks build that wrote all files, but failed size budgets or duplicate packages check
+var errBuildChecks = errors.New("build checks failed")
+
 func build() error {
 	start := time.Now()
 
 	// prepare esbuild build options
 	buildEsbuildConfig(true)
 
+	if err := updateEnvTypes(); err != nil {
+		return err
+	}
+
 	lib.PrintOk("Init done")
 	lib.PrintInfof("Time: %dms\n", time.Since(start).Milliseconds())
 
@@ -34,7 +42,7 @@ func build() error {
 
 	// copy static directory to build directory
 	if config.StaticDir != "" {
-		err = lib.CopyDir(config.OutputDir, config.StaticDir)
+		err = lib.CopyDirInterpolated(config.OutputDir, config.StaticDir, config.Interpolate, interpolationVars)
 		if err != nil {
 			return errors.Join(errors.New("failed to copy static directory"), err)
 		}
@@ -76,21 +84,49 @@ func build() error {
 			lib.PrintError("failed to save metafile", err)
 		} else {
 			lib.PrintOk("Metafile saved to 'build-meta.json'")
-			lib.PrintInfof("use e.g. https://esbuild.github.io/analyze/ to analyze the bundle\n")
+			lib.PrintInfof("use 'analyze' command to analyze the bundle\n")
 			lib.PrintInfof("Time: %dms\n", time.Since(start).Milliseconds())
 		}
 	}
 
+	var metafile Metadata
+	err = json.Unmarshal([]byte(result.Metafile), &metafile)
+	if err != nil {
+		return errors.Join(errors.New("failed to parse build metadata"), err)
+	}
+	files := bundleFiles(metafile, result.OutputFiles, config.OutputDir)
+	sizeReport(files, start.Unix())
+
 	err = os.WriteFile(filepath.Join(config.OutputDir, "version.json"), fmt.Appendf(nil, "{\"hash\":\"%s\",\"time\":%d}", versionData, start.Unix()), 0644)
 	if err != nil {
 		lib.PrintError("failed to save version.json", err)
 	}
 
-	lib.PrintItem("Building index.html file...")
-	err = makeIndex(config.PreloadPathsStartingWith, &result)
+	for _, page := range config.EntryPages() {
+		lib.PrintItemf("Building %s file...\n", page.Output)
+		err = makeIndex(page, config.PreloadPathsStartingWith, &result)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.CSP != "" && config.CSPMode == "headers" {
+		err = writeCSPHeaders(config.EntryPages())
+		if err != nil {
+			return err
+		}
+		lib.PrintOk("Content security policy saved to '_headers'")
+	}
+
+	// both checks report before build fails
+	err = checkDuplicates(metafile)
+	if config.Budgets.IsSet() {
+		err = errors.Join(err, checkBudgets(files))
+	}
 	if err != nil {
-		return err
+		return errors.Join(errBuildChecks, err)
 	}
+
 	lib.PrintOk("Build done")
 	lib.PrintInfof("Time: %dms\n", time.Since(start).Milliseconds())
 
@@ -99,24 +135,56 @@ func build() error {
 	return nil
 }
 
-func makeIndex(preloadPathsStartingWith lib.ArrayFlags, result *api.BuildResult) error {
+func makeIndex(page lib.Page, preloadPathsStartingWith lib.ArrayFlags, result *api.BuildResult) error {
 	var metafile Metadata
 	err := json.Unmarshal([]byte(result.Metafile), &metafile)
 	if err != nil {
 		return errors.Join(errors.New("failed to parse build metadata"), err)
 	}
 
-	indexFile, err := os.ReadFile(filepath.Join(config.OutputDir, "index.html"))
+	indexFile, err := readPageTemplate(page)
 	if err != nil {
-		indexFile, err = os.ReadFile(filepath.Join(baseDir, "index.html"))
+		return errors.Join(fmt.Errorf("failed to read build %s", page.Output), err)
+	}
 
+	if config.HTMLTemplate {
+		indexFile, err = lib.RenderIndexTemplate(page.Output, indexFile, indexTemplateData(metafile, config.OutputDir))
 		if err != nil {
-			return errors.Join(errors.New("failed to read build index.html"), err)
+			return errors.Join(fmt.Errorf("failed to render %s template", page.Output), err)
 		}
 	}
 
+	entryOutput, ok := findEntryOutput(metafile, filepath.Join(config.SourceDir, page.Entry))
+	if !ok {
+		return fmt.Errorf("failed to find build output of '%s' entry", page.Entry)
+	}
+	jsPath := outputPath(entryOutput, config.OutputDir)
+	cssPath := ""
+	if cssBundle := metafile.Outputs[entryOutput].CssBundle; cssBundle != "" {
+		cssPath = outputPath(cssBundle, config.OutputDir)
+	}
+
+	// replace %PUBLIC_URL%, %NODE_ENV% and prefixed env variables
+	indexFile, saveIndexFile := lib.InterpolateVars(indexFile, interpolationVars)
+
 	//inject main js/css if not already in index.html
-	indexFile, saveIndexFile := lib.InjectVarsIntoIndex(indexFile, config.EntryFileName, config.AssetsDir, config.PublicURL)
+	indexFile, injected := lib.InjectAssetsIntoIndex(indexFile, jsPath, cssPath, config.PublicURL)
+	saveIndexFile = saveIndexFile || injected
+
+	// preload chunks needed by entry at startup, prefetch lazy ones
+	if config.AutoPreload || config.Prefetch {
+		staticChunks, dynamicChunks := entryChunks(metafile, entryOutput)
+		if config.AutoPreload {
+			indexFile, injected = lib.InjectLinksIntoIndex(indexFile, "modulepreload", outputPaths(staticChunks, config.OutputDir), config.PublicURL)
+			saveIndexFile = saveIndexFile || injected
+		}
+		if config.Prefetch {
+			indexFile, injected = lib.InjectLinksIntoIndex(indexFile, "prefetch", outputPaths(dynamicChunks, config.OutputDir), config.PublicURL)
+			saveIndexFile = saveIndexFile || injected
+		}
+	}
+	// page html may not be in output yet, if not copied from static dir
+	saveIndexFile = saveIndexFile || !lib.FileExists(filepath.Join(config.OutputDir, page.Output))
 
 	// find chunks to preload
 	if len(preloadPathsStartingWith) > 0 {
@@ -134,32 +202,420 @@ func makeIndex(preloadPathsStartingWith lib.ArrayFlags, result *api.BuildResult)
 			}
 		}
 
-		if len(chunksToPreload) > 0 {
-			publicURL := strings.TrimSuffix(config.PublicURL, "/")
-			indexFileName := strings.TrimSuffix(filepath.Base(config.EntryFileName), filepath.Ext(config.EntryFileName))
-			findP := regexp.MustCompile(fmt.Sprintf("<link rel=([\"']?)modulepreload([\"']?) href=([\"']?)%s/%s/%s\\.js([\"']?)( ?/?)>(\n?)", publicURL, config.AssetsDir, indexFileName))
-			saveIndexFile = true
-			replace := strings.Builder{}
-			for chunk := range chunksToPreload {
-				fmt.Fprintf(&replace, "<link rel=${1}modulepreload${2} href=${3}%s/%s${4}${5}>${6}", publicURL, strings.ReplaceAll(chunk, filepath.Join(config.OutputDir, config.AssetsDir), config.AssetsDir))
-			}
-			// replace modulepreload index.js with modulepreload index.js and others
-			indexFile = findP.ReplaceAll(indexFile, []byte(replace.String()))
+		chunks := slices.Sorted(maps.Keys(chunksToPreload))
+		var injected bool
+		indexFile, injected = lib.InjectLinksIntoIndex(indexFile, "modulepreload", outputPaths(chunks, config.OutputDir), config.PublicURL)
+		saveIndexFile = saveIndexFile || injected
+
+		// matched chunks replace entry preload, entry keeps it only if it matches too
+		if len(chunks) > 0 && !chunksToPreload[entryOutput] {
+			indexFile, injected = lib.RemoveLinkFromIndex(indexFile, "modulepreload", jsPath, config.PublicURL)
+			saveIndexFile = saveIndexFile || injected
 		}
 	}
 
+	// subresource integrity for every tag loading built js/css, template tags included
+	if config.SRI {
+		var injected bool
+		indexFile, injected = lib.InjectIntegrityIntoIndex(indexFile, outputIntegrity(result.OutputFiles, config.OutputDir), config.PublicURL)
+		saveIndexFile = saveIndexFile || injected
+	}
+
+	// csp meta allowing inline scripts/styles by hash, must go last so hashes match final html,
+	// policy of csp meta in template is merged in before hashes, so they are allowed by its directives too
+	if config.CSP != "" && config.CSPMode == "meta" {
+		var injected bool
+		policy := lib.ParseCSP(config.CSP).Merge(lib.CSPMetaOfIndex(indexFile))
+		indexFile, injected = lib.InjectCSPMetaIntoIndex(indexFile, inlinePolicy(policy, indexFile).String())
+		saveIndexFile = saveIndexFile || injected
+	}
+
 	if saveIndexFile {
-		err = os.WriteFile(filepath.Join(config.OutputDir, "index.html"), indexFile, 0644)
+		outputFile := filepath.Join(config.OutputDir, page.Output)
+		err = os.MkdirAll(filepath.Dir(outputFile), 0755)
+		if err == nil {
+			err = os.WriteFile(outputFile, indexFile, 0644)
+		}
 		if err != nil {
-			return errors.Join(errors.New("failed to write built index.html"), err)
+			return errors.Join(fmt.Errorf("failed to write built %s", page.Output), err)
 		}
 	} else {
-		lib.PrintItem("No changes to index.html")
+		lib.PrintItemf("No changes to %s\n", page.Output)
 	}
 
 	return nil
 }
 
+// sizeReport prints sizes of built files with change since previous build, snapshot for next build goes to '.nrb/last-build.json'
+func sizeReport(files []lib.BundleFile, time int64) {
+	snapshotFile := filepath.Join(baseDir, ".nrb", "last-build.json")
+
+	var previous *lib.SizeSnapshot
+	if data, err := os.ReadFile(snapshotFile); err == nil {
+		var snapshot lib.SizeSnapshot
+		if err = json.Unmarshal(data, &snapshot); err == nil {
+			previous = &snapshot
+		}
+	}
+
+	current := lib.NewSizeSnapshot(files, time)
+	lib.PrintTable(lib.SizeReport(&current, previous))
+
+	data, err := json.Marshal(current)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(snapshotFile), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(snapshotFile, data, 0644)
+	}
+	if err != nil {
+		lib.PrintError("failed to save build size snapshot", err)
+	}
+}
+
+// checkBudgets prints table of files over 'budgets' and fails, so CI catches size regressions
+func checkBudgets(files []lib.BundleFile) error {
+	violations := config.Budgets.Check(files)
+	if len(violations) == 0 {
+		lib.PrintOk("Bundle size is within budgets")
+		return nil
+	}
+
+	lib.PrintError("bundle size is over budgets")
+	rows := [][]string{{"BUDGET", "FILE", "SIZE", "LIMIT", "OVER"}}
+	for _, v := range violations {
+		rows = append(rows, []string{
+			v.Budget + " (" + v.Kind + ")",
+			v.Path,
+			lib.FormatSize(v.Size),
+			lib.FormatSize(v.Limit),
+			"+" + lib.FormatSize(v.Size-v.Limit),
+		})
+	}
+	lib.PrintTable(rows)
+
+	return fmt.Errorf("%d size budget(s) exceeded", len(violations))
+}
+
+// checkDuplicates reports packages bundled more than once, fails on ones not in 'duplicates.allow' if 'duplicates.fail' is set
+func checkDuplicates(metafile Metadata) error {
+	inputBytes := make(map[string]int64)
+	for _, m := range metafile.Outputs {
+		for input, i := range m.Inputs {
+			inputBytes[input] += int64(i.BytesInOutput)
+		}
+	}
+
+	duplicates := lib.FindDuplicatePackages(inputBytes, packageVersion)
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	var wasted int64
+	var notAllowed []string
+	rows := [][]string{{"PACKAGE", "VERSION", "PATH", "SIZE"}}
+	for _, duplicate := range duplicates {
+		wasted += duplicate.Wasted()
+		name := duplicate.Name
+		if config.Duplicates.IsAllowed(name) {
+			name += " (allowed)"
+		} else {
+			notAllowed = append(notAllowed, duplicate.Name)
+		}
+		for i, c := range duplicate.Copies {
+			if i > 0 {
+				name = ""
+			}
+			rows = append(rows, []string{name, c.Version, c.Dir, lib.FormatSize(c.Bytes)})
+		}
+	}
+
+	lib.PrintWarnf("%d package(s) bundled more than once, %s in extra copies\n", len(duplicates), lib.FormatSize(wasted))
+	lib.PrintTable(rows)
+
+	if config.Duplicates.Fail && len(notAllowed) > 0 {
+		return fmt.Errorf("duplicate packages not in 'duplicates.allow': %s", strings.Join(notAllowed, ", "))
+	}
+	return nil
+}
+
+// packageVersion reads version of package in dir, metafile paths are relative to working dir
+func packageVersion(dir string) string {
+	data, err := os.ReadFile(filepath.Join(filepath.FromSlash(dir), "package.json"))
+	if err != nil {
+		return "?"
+	}
+	var pkg struct {
+		Version string `json:"version"`
+	}
+	if err = json.Unmarshal(data, &pkg); err != nil || pkg.Version == "" {
+		return "?"
+	}
+	return pkg.Version
+}
+
+// bundleFiles lists built files from metafile outputs with raw, gzip and brotli size, source maps are skipped
+func bundleFiles(metafile Metadata, files []api.OutputFile, outputRoot string) []lib.BundleFile {
+	contents := make(map[string][]byte, len(files))
+	for _, file := range files {
+		contents[file.Path] = file.Contents
+	}
+
+	// dynamic imports have entry point in metafile too, entry chunks are the page ones
+	entries := make(map[string]bool)
+	for _, page := range config.EntryPages() {
+		if out, ok := findEntryOutput(metafile, filepath.Join(config.SourceDir, page.Entry)); ok {
+			entries[out] = true
+		}
+	}
+
+	bundle := make([]lib.BundleFile, 0, len(metafile.Outputs))
+	for out, m := range metafile.Outputs {
+		if filepath.Ext(out) == ".map" {
+			continue
+		}
+		file := lib.BundleFile{
+			Path:  strings.TrimPrefix(outputPath(out, outputRoot), "/"),
+			Entry: entries[out],
+			Raw:   int64(m.Bytes),
+		}
+		for input := range m.Inputs {
+			file.Inputs = append(file.Inputs, input)
+		}
+		// output files have absolute paths, metafile ones are relative to working dir
+		if absOut, err := filepath.Abs(filepath.FromSlash(out)); err == nil {
+			if content, ok := contents[absOut]; ok {
+				file.Gzip = lib.GzipSize(content)
+				file.Brotli = lib.BrotliSize(content)
+			}
+		}
+		bundle = append(bundle, file)
+	}
+	slices.SortFunc(bundle, func(a, b lib.BundleFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return bundle
+}
+
+// loadMetafile reads 'build-meta.json' from output dir, the app is built first if there is none or rebuild is set
+func loadMetafile(rebuild bool) ([]byte, Metadata, error) {
+	var metafile Metadata
+	metafilePath := filepath.Join(config.OutputDir, "build-meta.json")
+	if rebuild || !lib.FileExists(metafilePath) {
+		// build reloads config, so metafile goes through overrides
+		configOverrides.Metafile = lib.OptionalBool{Value: true, Set: true}
+		// failed checks are what analysis is for, metafile is written anyway
+		if err := build(); errors.Is(err, errBuildChecks) {
+			lib.PrintWarn("build failed checks, analyzing it anyway")
+		} else if err != nil {
+			return nil, metafile, err
+		}
+	} else {
+		lib.PrintInfo("Using", metafilePath, "from last build, use -build to rebuild")
+	}
+
+	metafileJSON, err := os.ReadFile(metafilePath)
+	if err != nil {
+		return nil, metafile, errors.Join(errors.New("failed to read build metadata"), err)
+	}
+	if err = json.Unmarshal(metafileJSON, &metafile); err != nil {
+		return nil, metafile, errors.Join(errors.New("failed to parse build metadata"), err)
+	}
+	return metafileJSON, metafile, nil
+}
+
+// findEntryOutputs finds entry js and its css bundle in metafile outputs, returned paths are relative to outputRoot
+func findEntryOutputs(metafile Metadata, entry, outputRoot string) (jsPath, cssPath string, ok bool) {
+	out, ok := findEntryOutput(metafile, entry)
+	if !ok {
+		return "", "", false
+	}
+
+	if cssBundle := metafile.Outputs[out].CssBundle; cssBundle != "" {
+		cssPath = outputPath(cssBundle, outputRoot)
+	}
+	return outputPath(out, outputRoot), cssPath, true
+}
+
+// findEntryOutput finds metafile output of entry js
+func findEntryOutput(metafile Metadata, entry string) (string, bool) {
+	absEntry, err := filepath.Abs(entry)
+	if err != nil {
+		return "", false
+	}
+
+	for out, m := range metafile.Outputs {
+		if m.EntryPoint == "" || !strings.HasSuffix(out, ".js") {
+			continue
+		}
+		// metafile paths are relative to working dir
+		if absEntryPoint, err := filepath.Abs(filepath.FromSlash(m.EntryPoint)); err == nil && absEntryPoint == absEntry {
+			return out, true
+		}
+	}
+
+	return "", false
+}
+
+// entryChunks walks output imports from entry, staticChunks are loaded with entry at startup, dynamicChunks later by import()
+func entryChunks(metafile Metadata, entryOutput string) (staticChunks, dynamicChunks []string) {
+	// follows import-statement edges from start, returns chunks not seen yet in import order
+	walk := func(start []string, seen map[string]bool) []string {
+		var found []string
+		queue := start
+		for len(queue) > 0 {
+			chunk := queue[0]
+			queue = queue[1:]
+			for _, imp := range metafile.Outputs[chunk].Imports {
+				if imp.External || imp.Kind != "import-statement" || seen[imp.Path] {
+					continue
+				}
+				if _, ok := metafile.Outputs[imp.Path]; !ok {
+					continue
+				}
+				seen[imp.Path] = true
+				found = append(found, imp.Path)
+				queue = append(queue, imp.Path)
+			}
+		}
+		return found
+	}
+
+	seen := map[string]bool{entryOutput: true}
+	staticChunks = walk([]string{entryOutput}, seen)
+
+	// lazy chunks and everything they import that is not loaded at startup
+	for _, chunk := range append([]string{entryOutput}, staticChunks...) {
+		for _, imp := range metafile.Outputs[chunk].Imports {
+			if imp.External || imp.Kind != "dynamic-import" || seen[imp.Path] {
+				continue
+			}
+			if _, ok := metafile.Outputs[imp.Path]; !ok {
+				continue
+			}
+			seen[imp.Path] = true
+			dynamicChunks = append(dynamicChunks, imp.Path)
+			dynamicChunks = append(dynamicChunks, walk([]string{imp.Path}, seen)...)
+		}
+	}
+
+	return staticChunks, dynamicChunks
+}
+
+// outputIntegrity hashes built js/css files by url path from outputRoot
+func outputIntegrity(files []api.OutputFile, outputRoot string) map[string]string {
+	integrity := make(map[string]string)
+	for _, file := range files {
+		switch filepath.Ext(file.Path) {
+		case ".js", ".css":
+			integrity[outputPath(file.Path, outputRoot)] = lib.IntegrityHash(file.Contents)
+		}
+	}
+	return integrity
+}
+
+func outputPaths(outs []string, outputRoot string) []string {
+	paths := make([]string, len(outs))
+	for i, out := range outs {
+		paths[i] = outputPath(out, outputRoot)
+	}
+	return paths
+}
+
+// outputPath converts metafile output path to url path from outputRoot
+func outputPath(out, outputRoot string) string {
+	absOut, _ := filepath.Abs(filepath.FromSlash(out))
+	absRoot, _ := filepath.Abs(outputRoot)
+	rel, err := filepath.Rel(absRoot, absOut)
+	if err != nil {
+		return "/" + out
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+// indexTemplateData is data for index.html go template, chunks are built js/css files
+func indexTemplateData(metafile Metadata, outputRoot string) lib.IndexData {
+	var chunks []lib.IndexChunk
+	for out, m := range metafile.Outputs {
+		if ext := filepath.Ext(out); ext != ".js" && ext != ".css" {
+			continue
+		}
+		chunks = append(chunks, lib.IndexChunk{
+			Path:       strings.TrimSuffix(config.PublicURL, "/") + outputPath(out, outputRoot),
+			EntryPoint: m.EntryPoint,
+			Bytes:      int(m.Bytes),
+		})
+	}
+	slices.SortFunc(chunks, func(a, b lib.IndexChunk) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return lib.IndexData{
+		Config:  *config,
+		Mode:    appMode,
+		Version: versionData,
+		Env:     prefixedEnv(config.EnvPrefix),
+		Chunks:  chunks,
+	}
+}
+
+// inlinePolicy allows inline scripts and styles of index.html content in policy by their hashes
+func inlinePolicy(policy lib.CSP, indexFile []byte) lib.CSP {
+	scripts, styles := lib.InlineHashes(indexFile)
+	if len(scripts) > 0 {
+		policy = policy.AddSources("script-src", scripts...)
+	}
+	if len(styles) > 0 {
+		policy = policy.AddSources("style-src", styles...)
+	}
+	return policy
+}
+
+// writeCSPHeaders appends policy allowing inline scripts/styles of all built pages to '_headers' file in output dir
+func writeCSPHeaders(pages []lib.Page) error {
+	policy := lib.ParseCSP(config.CSP)
+	for _, page := range pages {
+		indexFile, err := os.ReadFile(filepath.Join(config.OutputDir, page.Output))
+		if err != nil {
+			return errors.Join(fmt.Errorf("failed to read built %s", page.Output), err)
+		}
+		policy = inlinePolicy(policy, indexFile)
+	}
+
+	headersFile, err := os.OpenFile(filepath.Join(config.OutputDir, "_headers"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Join(errors.New("failed to write _headers"), err)
+	}
+	defer func() { _ = headersFile.Close() }()
+
+	// keep rules from static '_headers' apart
+	separator := ""
+	if stat, err := headersFile.Stat(); err == nil && stat.Size() > 0 {
+		separator = "\n"
+	}
+
+	_, err = fmt.Fprintf(headersFile, "%s/*\n  Content-Security-Policy: %s\n", separator, policy)
+	if err != nil {
+		return errors.Join(errors.New("failed to write _headers"), err)
+	}
+	return nil
+}
+
+// readPageTemplate reads page html template, pages without template use index.html from static dir or base dir,
+// never from output dir, as built pages are written there
+func readPageTemplate(page lib.Page) ([]byte, error) {
+	if page.Template != "" {
+		return os.ReadFile(page.Template)
+	}
+
+	if config.StaticDir != "" {
+		if indexFile, err := os.ReadFile(filepath.Join(config.StaticDir, "index.html")); err == nil {
+			return indexFile, nil
+		}
+	}
+	return os.ReadFile(filepath.Join(baseDir, "index.html"))
+}
+
 // Metadata is json equivalent of this esbuild metadata interface
 //
 //		interface Metadata {
@@ -186,6 +642,7 @@ func makeIndex(preloadPathsStartingWith lib.ArrayFlags, result *api.BuildResult)
 //		     imports: {
 //		       path: string
 //		       kind: string
+//	          external?: boolean
 //		     }[]
 //		     exports: string[]
 //		     entryPoint?: string
@@ -210,8 +667,9 @@ type Metadata struct {
 			BytesInOutput float64 `json:"bytesInOutput"`
 		} `json:"inputs"`
 		Imports []struct {
-			Path string `json:"path"`
-			Kind string `json:"kind"`
+			Path     string `json:"path"`
+			Kind     string `json:"kind"`
+			External bool   `json:"external"`
 		} `json:"imports"`
 		Exports    []string `json:"exports"`
 		EntryPoint string   `json:"entryPoint"`
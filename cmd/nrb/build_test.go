@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/natrim/nrb/lib"
+)
+
+// metafilePath converts dir to path in metafile, which is relative to working dir
+func metafilePath(t *testing.T, dir string) string {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working dir: %v", err)
+	}
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		t.Fatalf("failed to make %s relative to working dir: %v", dir, err)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func TestMakeIndexInjectsHashedEntryOutputs(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+
+	dir := t.TempDir()
+	config.SourceDir = filepath.Join(dir, "src")
+	config.OutputDir = filepath.Join(dir, "build")
+	config.StaticDir = filepath.Join(dir, "public")
+	_ = os.MkdirAll(config.StaticDir, 0755)
+	writeFile(t, filepath.Join(config.StaticDir, "index.html"), "<html><head></head><body></body></html>")
+	writeFile(t, filepath.Join(dir, "admin.html"), "<html><head></head><body></body></html>")
+
+	rel := metafilePath(t, dir)
+	result := api.BuildResult{Metafile: fmt.Sprintf(`{"outputs":{
+		"%[1]s/build/assets/index-AAA.js": {"entryPoint": "%[1]s/src/index.tsx", "cssBundle": "%[1]s/build/assets/index-BBB.css"},
+		"%[1]s/build/assets/index-BBB.css": {},
+		"%[1]s/build/assets/admin-CCC.js": {"entryPoint": "%[1]s/src/admin.tsx"}
+	}}`, rel)}
+
+	pages := []lib.Page{
+		{Name: "index", Entry: "index.tsx", Output: "index.html"},
+		{Name: "admin", Entry: "admin.tsx", Template: filepath.Join(dir, "admin.html"), Output: "admin/index.html"},
+	}
+	for _, page := range pages {
+		if err := makeIndex(page, nil, &result); err != nil {
+			t.Fatalf("makeIndex(%s) returned error: %v", page.Name, err)
+		}
+	}
+
+	index, _ := os.ReadFile(filepath.Join(config.OutputDir, "index.html"))
+	for _, want := range []string{`<script type="module" src="/assets/index-AAA.js">`, `<link rel="stylesheet" href="/assets/index-BBB.css">`} {
+		if !strings.Contains(string(index), want) {
+			t.Fatalf("index.html = %q, want it to contain %q", index, want)
+		}
+	}
+
+	admin, _ := os.ReadFile(filepath.Join(config.OutputDir, "admin", "index.html"))
+	if !strings.Contains(string(admin), `<script type="module" src="/assets/admin-CCC.js">`) || strings.Contains(string(admin), "stylesheet") {
+		t.Fatalf("admin/index.html = %q, want only admin script", admin)
+	}
+}
+
+func TestMakeIndexReadsPagesWithoutTemplateFromStaticDir(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+
+	dir := t.TempDir()
+	config.SourceDir = filepath.Join(dir, "src")
+	config.OutputDir = filepath.Join(dir, "build")
+	config.StaticDir = filepath.Join(dir, "public")
+	_ = os.MkdirAll(config.StaticDir, 0755)
+	_ = os.MkdirAll(config.OutputDir, 0755)
+	// build copies static dir to output first
+	writeFile(t, filepath.Join(config.StaticDir, "index.html"), "<html><head></head><body></body></html>")
+	writeFile(t, filepath.Join(config.OutputDir, "index.html"), "<html><head></head><body></body></html>")
+
+	rel := metafilePath(t, dir)
+	result := api.BuildResult{Metafile: fmt.Sprintf(`{"outputs":{
+		"%[1]s/build/assets/index.js": {"entryPoint": "%[1]s/src/index.tsx", "cssBundle": "%[1]s/build/assets/index.css"},
+		"%[1]s/build/assets/index.css": {},
+		"%[1]s/build/assets/dash.js": {"entryPoint": "%[1]s/src/dash.tsx"}
+	}}`, rel)}
+
+	pages := []lib.Page{
+		{Name: "app", Entry: "index.tsx", Output: "index.html"},
+		{Name: "dash", Entry: "dash.tsx", Output: "dash/index.html"},
+	}
+	for _, page := range pages {
+		if err := makeIndex(page, nil, &result); err != nil {
+			t.Fatalf("makeIndex(%s) returned error: %v", page.Name, err)
+		}
+	}
+
+	dash, _ := os.ReadFile(filepath.Join(config.OutputDir, "dash", "index.html"))
+	if !strings.Contains(string(dash), `<script type="module" src="/assets/dash.js">`) ||
+		strings.Contains(string(dash), "/assets/index.js") || strings.Contains(string(dash), "/assets/index.css") {
+		t.Fatalf("dash/index.html = %q, want only dash script", dash)
+	}
+}
+
+func TestMakeIndexPreloadsOnlyChunksMatchingPreloadPaths(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+
+	dir := t.TempDir()
+	config.SourceDir = filepath.Join(dir, "src")
+	config.OutputDir = filepath.Join(dir, "build")
+	config.StaticDir = filepath.Join(dir, "public")
+	_ = os.MkdirAll(config.StaticDir, 0755)
+	writeFile(t, filepath.Join(config.StaticDir, "index.html"), "<html><head></head><body></body></html>")
+
+	rel := metafilePath(t, dir)
+	result := api.BuildResult{Metafile: fmt.Sprintf(`{"outputs":{
+		"%[1]s/build/assets/index.js": {"entryPoint": "%[1]s/src/index.tsx", "inputs": {"%[1]s/src/index.tsx": {"bytesInOutput": 10}}},
+		"%[1]s/build/assets/chunks/react.js": {"inputs": {"node_modules/react/index.js": {"bytesInOutput": 10}}}
+	}}`, rel)}
+
+	page := lib.Page{Name: "index", Entry: "index.tsx", Output: "index.html"}
+	for _, tt := range []struct {
+		preload      lib.ArrayFlags
+		entryPreload bool
+	}{
+		// matched chunks replace entry preload
+		{lib.ArrayFlags{"node_modules/react"}, false},
+		{lib.ArrayFlags{"node_modules/react", rel + "/src/index"}, true},
+	} {
+		if err := makeIndex(page, tt.preload, &result); err != nil {
+			t.Fatalf("makeIndex(%v) returned error: %v", tt.preload, err)
+		}
+
+		index, _ := os.ReadFile(filepath.Join(config.OutputDir, "index.html"))
+		if !strings.Contains(string(index), `<link rel="modulepreload" href="/assets/chunks/react.js">`) {
+			t.Fatalf("index.html = %q, want react chunk preloaded", index)
+		}
+		if got := strings.Contains(string(index), `<link rel="modulepreload" href="/assets/index.js">`); got != tt.entryPreload {
+			t.Fatalf("index.html = %q with preload %v, want entry preload %v", index, tt.preload, tt.entryPreload)
+		}
+		if !strings.Contains(string(index), `<script type="module" src="/assets/index.js">`) {
+			t.Fatalf("index.html = %q, want entry script", index)
+		}
+	}
+}
+
+func TestEntryChunksFollowsStaticImportsAndCollectsLazyChunks(t *testing.T) {
+	var metafile Metadata
+	err := json.Unmarshal([]byte(`{"outputs":{
+		"build/assets/index.js": {"entryPoint": "src/index.tsx", "imports": [
+			{"path": "build/assets/chunks/shared.js", "kind": "import-statement"},
+			{"path": "build/assets/chunks/lazy.js", "kind": "dynamic-import"},
+			{"path": "react", "kind": "import-statement", "external": true}
+		]},
+		"build/assets/chunks/shared.js": {"imports": [{"path": "build/assets/chunks/vendor.js", "kind": "import-statement"}]},
+		"build/assets/chunks/vendor.js": {},
+		"build/assets/chunks/lazy.js": {"imports": [
+			{"path": "build/assets/chunks/shared.js", "kind": "import-statement"},
+			{"path": "build/assets/chunks/lazydep.js", "kind": "import-statement"}
+		]},
+		"build/assets/chunks/lazydep.js": {}
+	}}`), &metafile)
+	if err != nil {
+		t.Fatalf("failed to parse metafile: %v", err)
+	}
+
+	staticChunks, dynamicChunks := entryChunks(metafile, "build/assets/index.js")
+
+	wantStatic := []string{"build/assets/chunks/shared.js", "build/assets/chunks/vendor.js"}
+	if !reflect.DeepEqual(staticChunks, wantStatic) {
+		t.Fatalf("staticChunks = %v, want %v", staticChunks, wantStatic)
+	}
+	wantDynamic := []string{"build/assets/chunks/lazy.js", "build/assets/chunks/lazydep.js"}
+	if !reflect.DeepEqual(dynamicChunks, wantDynamic) {
+		t.Fatalf("dynamicChunks = %v, want %v", dynamicChunks, wantDynamic)
+	}
+}
+
+func TestMakeIndexRendersHTMLTemplate(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+	t.Setenv("REACT_APP_TITLE", "Shop")
+
+	dir := t.TempDir()
+	config.SourceDir = filepath.Join(dir, "src")
+	config.OutputDir = filepath.Join(dir, "build")
+	config.HTMLTemplate = true
+	appMode = "production"
+	versionData = "abc"
+	config.StaticDir = filepath.Join(dir, "public")
+	_ = os.MkdirAll(config.StaticDir, 0755)
+	writeFile(t, filepath.Join(config.StaticDir, "index.html"), `<html><head><title>{{.Env.REACT_APP_TITLE}} {{.Version}}</title>`+
+		`{{if eq .Mode "production"}}<script src="/analytics.js"></script>{{end}}{{if .Env.REACT_APP_MISSING}}missing{{end}}`+
+		`{{range .Chunks}}{{if not .EntryPoint}}<link rel="prefetch" href="{{.Path}}">{{end}}{{end}}</head><body></body></html>`)
+
+	rel := metafilePath(t, dir)
+	result := api.BuildResult{Metafile: fmt.Sprintf(`{"outputs":{
+		"%[1]s/build/assets/index.js": {"entryPoint": "%[1]s/src/index.tsx"},
+		"%[1]s/build/assets/index.js.map": {},
+		"%[1]s/build/assets/chunks/lazy.js": {}
+	}}`, rel)}
+
+	if err := makeIndex(lib.Page{Name: "index", Entry: "index.tsx", Output: "index.html"}, nil, &result); err != nil {
+		t.Fatalf("makeIndex() returned error: %v", err)
+	}
+
+	index, _ := os.ReadFile(filepath.Join(config.OutputDir, "index.html"))
+	for _, want := range []string{"<title>Shop abc</title>", `<script src="/analytics.js">`, `<link rel="prefetch" href="/assets/chunks/lazy.js">`} {
+		if !strings.Contains(string(index), want) {
+			t.Fatalf("index.html = %q, want it to contain %q", index, want)
+		}
+	}
+	if strings.Contains(string(index), "missing") || strings.Contains(string(index), ".map") {
+		t.Fatalf("index.html = %q, want no missing env or map chunk", index)
+	}
+
+	writeFile(t, filepath.Join(config.StaticDir, "index.html"), "{{.Nope}}")
+	if err := makeIndex(lib.Page{Name: "index", Entry: "index.tsx", Output: "index.html"}, nil, &result); err == nil {
+		t.Fatal("expected template error")
+	}
+}
+
+func TestCheckBudgetsMeasuresOutputsAndFailsOverLimit(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+
+	dir := t.TempDir()
+	config.SourceDir = filepath.Join(dir, "src")
+	config.EntryFileName = "index.tsx"
+	config.OutputDir = filepath.Join(dir, "build")
+
+	rel := metafilePath(t, dir)
+	entry := []byte(strings.Repeat("console.log('hello');\n", 100))
+	result := api.BuildResult{
+		Metafile: fmt.Sprintf(`{"outputs":{
+			"%[1]s/build/assets/index-AAA.js": {"entryPoint": "%[1]s/src/index.tsx", "bytes": %[2]d},
+			"%[1]s/build/assets/index-AAA.js.map": {"bytes": 99999},
+			"%[1]s/build/assets/lazy-BBB.js": {"entryPoint": "%[1]s/src/lazy.ts", "bytes": 10}
+		}}`, rel, len(entry)),
+		OutputFiles: []api.OutputFile{{Path: filepath.Join(config.OutputDir, "assets", "index-AAA.js"), Contents: entry}},
+	}
+
+	var metafile Metadata
+	_ = json.Unmarshal([]byte(result.Metafile), &metafile)
+	files := bundleFiles(metafile, result.OutputFiles, config.OutputDir)
+	if len(files) != 2 || files[0].Path != "assets/index-AAA.js" || !files[0].Entry || files[0].Raw != int64(len(entry)) || files[1].Entry {
+		t.Fatalf("bundleFiles() = %+v, want entry js and lazy chunk", files)
+	}
+	if files[0].Gzip == 0 || files[0].Gzip >= files[0].Raw || files[0].Brotli == 0 || files[0].Brotli >= files[0].Raw {
+		t.Fatalf("bundleFiles() gzip size = %d, brotli size = %d, want compressed sizes", files[0].Gzip, files[0].Brotli)
+	}
+
+	config.Budgets = lib.Budgets{Entry: lib.Budget{Raw: int64(len(entry))}}
+	if err := checkBudgets(files); err != nil {
+		t.Fatalf("checkBudgets() returned error within budget: %v", err)
+	}
+
+	config.Budgets = lib.Budgets{Entry: lib.Budget{Gzip: 10}}
+	if err := checkBudgets(files); err == nil {
+		t.Fatal("checkBudgets() expected error over gzip budget")
+	}
+}
+
+func TestCheckDuplicatesFailsOnPackagesNotAllowed(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+
+	dir := t.TempDir()
+	for pkg, version := range map[string]string{
+		"node_modules/lodash":                "4.17.21",
+		"node_modules/a/node_modules/lodash": "4.17.15",
+		"node_modules/tslib":                 "2.6.0",
+		"node_modules/b/node_modules/tslib":  "2.6.0",
+	} {
+		_ = os.MkdirAll(filepath.Join(dir, pkg), 0755)
+		writeFile(t, filepath.Join(dir, pkg, "package.json"), `{"version": "`+version+`"}`)
+	}
+
+	rel := metafilePath(t, dir)
+	if got := packageVersion(rel + "/node_modules/a/node_modules/lodash"); got != "4.17.15" {
+		t.Fatalf("packageVersion() = %q, want 4.17.15", got)
+	}
+	if got := packageVersion(rel + "/node_modules/missing"); got != "?" {
+		t.Fatalf("packageVersion() of missing package = %q, want ?", got)
+	}
+
+	var metafile Metadata
+	err := json.Unmarshal(fmt.Appendf(nil, `{"outputs":{"build/index.js":{"inputs":{
+		"%[1]s/node_modules/lodash/get.js": {"bytesInOutput": 300},
+		"%[1]s/node_modules/a/node_modules/lodash/get.js": {"bytesInOutput": 200},
+		"%[1]s/node_modules/tslib/tslib.js": {"bytesInOutput": 50},
+		"%[1]s/node_modules/b/node_modules/tslib/tslib.js": {"bytesInOutput": 50}
+	}}}}`, rel), &metafile)
+	if err != nil {
+		t.Fatalf("failed to parse metafile: %v", err)
+	}
+
+	config.Duplicates = lib.Duplicates{Allow: []string{"tslib"}}
+	if err := checkDuplicates(metafile); err != nil {
+		t.Fatalf("checkDuplicates() without fail returned error: %v", err)
+	}
+
+	config.Duplicates.Fail = true
+	err = checkDuplicates(metafile)
+	if err == nil || !strings.Contains(err.Error(), "lodash") || strings.Contains(err.Error(), "tslib") {
+		t.Fatalf("checkDuplicates() = %v, want error about lodash only", err)
+	}
+}
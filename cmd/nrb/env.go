@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/natrim/nrb/lib"
+)
+
+// envCommand handles 'env' command with its own flags after it
+func envCommand(args []string) error {
+	flags := flag.NewFlagSet("env", flag.ContinueOnError)
+	typesFlag := false
+	jsonFlag := false
+	maskFlag := false
+	modeFlag := cliState.Mode
+	flags.BoolVar(&typesFlag, "types", typesFlag, "write typescript declarations of env variables to 'envTypes' file, defaults to 'sourceDir/nrb-env.d.ts'")
+	flags.BoolVar(&jsonFlag, "json", jsonFlag, "print defines as json, other output goes to stderr")
+	flags.BoolVar(&maskFlag, "mask", maskFlag, "mask values of env variables")
+	flags.StringVar(&modeFlag, "mode", modeFlag, "env mode to show, defaults to NODE_ENV, else production")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unknown argument '%s' of env command", flags.Arg(0))
+	}
+
+	if jsonFlag {
+		lib.UseStderr()
+	}
+
+	// same defines as build, or watch for development mode, env schema problems are reported after defines,
+	// so the audit shows what would ship even when build would fail
+	cliState.Mode = modeFlag
+	problems := prepareEsbuildConfig(resolveMode(true) != "development")
+
+	if typesFlag {
+		typesFile := config.EnvTypes
+		if typesFile == "" {
+			typesFile = filepath.Join(config.SourceDir, "nrb-env.d.ts")
+		}
+		written, err := writeEnvTypes(typesFile)
+		if err != nil {
+			return err
+		}
+		if written {
+			lib.PrintOk("Env types saved to", typesFile)
+		} else {
+			lib.PrintOk("Env types in", typesFile, "are up to date")
+		}
+		return envSchemaError(problems)
+	}
+
+	defines := envDefines(maskFlag)
+
+	if jsonFlag {
+		report, err := json.MarshalIndent(struct {
+			Mode     string      `json:"mode"`
+			EnvFiles []string    `json:"envFiles"`
+			Defines  []envDefine `json:"defines"`
+		}{appMode, loadedEnvFiles, defines}, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintln(os.Stdout, string(report)); err != nil {
+			return err
+		}
+		return envSchemaError(problems)
+	}
+
+	for _, define := range defines {
+		lib.PrintItemf("%s = %s %s\n", lib.Yellow(define.Key), define.Value, lib.Blue("("+define.Source+")"))
+	}
+	return envSchemaError(problems)
+}
+
+// envSchemaError warns about env variables not matching 'env' schema, returns error so env command exits with failure
+func envSchemaError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	printEnvSchemaProblems(problems, false)
+	return fmt.Errorf("%d env variable(s) do not match 'env' schema", len(problems))
+}
+
+// envDefine is one define baked into the bundle with source of its value
+type envDefine struct {
+	Key    string          `json:"key"`
+	Value  json.RawMessage `json:"value"`
+	Source string          `json:"source"`
+}
+
+// envDefines lists defines sorted by key, source is env file, 'environment', 'env schema default' or 'nrb' for values nrb makes
+func envDefines(mask bool) []envDefine {
+	defines := make([]envDefine, 0, len(definedReplacements))
+	for _, key := range slices.Sorted(maps.Keys(definedReplacements)) {
+		value := definedReplacements[key]
+		name := key
+		for _, prefix := range []string{"process.env.", "import.meta.env.", "import.meta."} {
+			if after, ok := strings.CutPrefix(key, prefix); ok {
+				name = after
+				break
+			}
+		}
+
+		source := "nrb"
+		if fromEnv := envVariableSource(name); fromEnv != "" {
+			source = fromEnv
+			if mask {
+				value = maskDefine(value)
+			}
+		} else if mask && key == "import.meta.env" {
+			value = maskMetaEnv(value)
+		}
+
+		defines = append(defines, envDefine{Key: key, Value: json.RawMessage(value), Source: source})
+	}
+	return defines
+}
+
+// envVariableSource returns where env variable value comes from, empty if nrb makes the value
+func envVariableSource(name string) string {
+	if name == config.EnvPrefix+"VERSION" {
+		return ""
+	}
+	if nodeEnv, _ := lookupEnv("NODE_ENV"); !strings.HasPrefix(name, config.EnvPrefix) && (name != "NODE_ENV" || nodeEnv == "") {
+		return ""
+	}
+	if source, ok := envSources[name]; ok {
+		return source
+	}
+	return "environment"
+}
+
+// maskDefine hides define value, only start of longer strings stays
+func maskDefine(value string) string {
+	var s string
+	if err := json.Unmarshal([]byte(value), &s); err != nil {
+		return `"****"`
+	}
+	masked := "****"
+	if len([]rune(s)) > 8 {
+		masked = string([]rune(s)[:2]) + masked
+	}
+	encoded, _ := json.Marshal(masked)
+	return string(encoded)
+}
+
+// maskMetaEnv hides env variable values in whole import.meta.env object
+func maskMetaEnv(value string) string {
+	var metaEnv map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &metaEnv); err != nil {
+		return value
+	}
+	for name, v := range metaEnv {
+		if envVariableSource(name) != "" {
+			metaEnv[name] = json.RawMessage(maskDefine(string(v)))
+		}
+	}
+	encoded, _ := json.Marshal(metaEnv)
+	return string(encoded)
+}
+
+// updateEnvTypes writes env types to 'envTypes' file on build and watch, if set
+func updateEnvTypes() error {
+	if config.EnvTypes == "" {
+		return nil
+	}
+	written, err := writeEnvTypes(config.EnvTypes)
+	if err != nil {
+		return err
+	}
+	if written {
+		lib.PrintOk("Env types saved to", config.EnvTypes)
+	}
+	return nil
+}
+
+// writeEnvTypes writes typescript declarations of defined env variables, file is not touched when unchanged, returns bool if written
+func writeEnvTypes(typesFile string) (bool, error) {
+	tsTypes, optional := envTypeHints()
+	types := lib.EnvTypes(definedReplacements, tsTypes, optional)
+
+	if current, err := os.ReadFile(typesFile); err == nil && bytes.Equal(current, types) {
+		return false, nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(typesFile), 0755)
+	if err == nil {
+		err = os.WriteFile(typesFile, types, 0644)
+	}
+	if err != nil {
+		return false, errors.Join(errors.New("failed to write env types"), err)
+	}
+	return true, nil
+}
+
+// envTypeHints are narrower typescript types of env variables and prefixed 'env' schema variables that are not set
+func envTypeHints() (types map[string]string, optional []string) {
+	types = map[string]string{
+		"NODE_ENV": lib.TSStringUnion([]string{"development", "production", "test"}),
+	}
+	for _, v := range config.EnvSchema {
+		types[v.Name] = v.TSType()
+		if _, ok := lookupEnv(v.Name); !ok && strings.HasPrefix(v.Name, config.EnvPrefix) {
+			optional = append(optional, v.Name)
+		}
+	}
+	return types, optional
+}
+
+// applyEnvSchema sets defaults of unset variables from 'env' schema to envDefaults, returns missing or malformed variables
+func applyEnvSchema() []string {
+	for name := range envDefaults {
+		delete(envSources, name)
+	}
+
+	defaults, problems := config.EnvSchema.Check(appMode, os.LookupEnv)
+	for name := range defaults {
+		envSources[name] = "env schema default"
+	}
+	envDefaults = defaults
+	return problems
+}
+
+// lookupEnv looks up env variable in environment, then in 'env' schema defaults
+func lookupEnv(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := envDefaults[name]
+	return value, ok
+}
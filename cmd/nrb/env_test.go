@@ -0,0 +1,168 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/natrim/nrb/lib"
+)
+
+func TestWriteEnvTypesSkipsUnchangedFile(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+	t.Setenv("REACT_APP_API", "/api")
+
+	if _, err := buildDefinedReplacements(*config, "development", false); err != nil {
+		t.Fatalf("buildDefinedReplacements() returned error: %v", err)
+	}
+
+	typesFile := filepath.Join(t.TempDir(), "src", "nrb-env.d.ts")
+	if written, err := writeEnvTypes(typesFile); err != nil || !written {
+		t.Fatalf("writeEnvTypes() = %v, %v, want true, nil", written, err)
+	}
+	types, _ := os.ReadFile(typesFile)
+	for _, want := range []string{`readonly NODE_ENV: "development" | "production" | "test";`, "readonly REACT_APP_API: string;", "readonly SSR: boolean;"} {
+		if !strings.Contains(string(types), want) {
+			t.Fatalf("env types = %s, want it to contain %q", types, want)
+		}
+	}
+
+	if written, err := writeEnvTypes(typesFile); err != nil || written {
+		t.Fatalf("writeEnvTypes() = %v, %v, want false, nil for unchanged types", written, err)
+	}
+}
+
+func TestApplyEnvSchemaSetsDefaultsAndDeclaresOptionalTypes(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+	for _, name := range []string{"REACT_APP_THEME", "REACT_APP_SENTRY"} {
+		t.Setenv(name, "")
+		_ = os.Unsetenv(name)
+	}
+
+	config.EnvSchema = lib.EnvSchema{
+		{Name: "REACT_APP_SENTRY", Type: "url"},
+		{Name: "REACT_APP_THEME", Type: "enum", Values: []string{"light", "dark"}, Default: "light", HasDefault: true},
+	}
+	appMode = "development"
+	if problems := applyEnvSchema(); len(problems) != 0 {
+		t.Fatalf("applyEnvSchema() = %v, want no problems", problems)
+	}
+	if got := envDefaults["REACT_APP_THEME"]; got != "light" {
+		t.Fatalf("REACT_APP_THEME = %q, want default %q", got, "light")
+	}
+	if _, ok := os.LookupEnv("REACT_APP_THEME"); ok {
+		t.Fatal("applyEnvSchema() changed process environment")
+	}
+
+	if _, err := buildDefinedReplacements(*config, appMode, false); err != nil {
+		t.Fatalf("buildDefinedReplacements() returned error: %v", err)
+	}
+	typesFile := filepath.Join(t.TempDir(), "nrb-env.d.ts")
+	if _, err := writeEnvTypes(typesFile); err != nil {
+		t.Fatalf("writeEnvTypes() returned error: %v", err)
+	}
+	types, _ := os.ReadFile(typesFile)
+	for _, want := range []string{`readonly REACT_APP_THEME: "light" | "dark";`, "readonly REACT_APP_SENTRY?: string;"} {
+		if !strings.Contains(string(types), want) {
+			t.Fatalf("env types = %s, want it to contain %q", types, want)
+		}
+	}
+}
+
+func TestApplyEnvSchemaReloadChangesAndDropsDefaults(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+	t.Setenv("REACT_APP_THEME", "")
+	_ = os.Unsetenv("REACT_APP_THEME")
+
+	appMode = "development"
+	for _, theme := range []string{"light", "dark"} {
+		config.EnvSchema = lib.EnvSchema{{Name: "REACT_APP_THEME", Default: theme, HasDefault: true}}
+		applyEnvSchema()
+		if _, err := buildDefinedReplacements(*config, appMode, false); err != nil {
+			t.Fatalf("buildDefinedReplacements() returned error: %v", err)
+		}
+		if got, want := definedReplacements["process.env.REACT_APP_THEME"], `"`+theme+`"`; got != want {
+			t.Fatalf("process.env.REACT_APP_THEME = %s, want %s", got, want)
+		}
+		if got := envSources["REACT_APP_THEME"]; got != "env schema default" {
+			t.Fatalf("REACT_APP_THEME source = %q, want schema default", got)
+		}
+	}
+
+	// default removed from package.json on reload
+	config.EnvSchema = lib.EnvSchema{{Name: "REACT_APP_THEME"}}
+	applyEnvSchema()
+	if _, err := buildDefinedReplacements(*config, appMode, false); err != nil {
+		t.Fatalf("buildDefinedReplacements() returned error: %v", err)
+	}
+	if got, ok := definedReplacements["process.env.REACT_APP_THEME"]; ok {
+		t.Fatalf("process.env.REACT_APP_THEME = %s, want removed default not defined", got)
+	}
+	if got, ok := envSources["REACT_APP_THEME"]; ok {
+		t.Fatalf("REACT_APP_THEME source = %q, want removed default dropped", got)
+	}
+}
+
+func TestEnvDefinesListsSourcesAndMasksValues(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+	baseDir = t.TempDir()
+	for _, name := range []string{"REACT_APP_FILE", "REACT_APP_LOCAL", "NODE_ENV"} {
+		t.Setenv(name, "")
+		_ = os.Unsetenv(name)
+	}
+	t.Setenv("REACT_APP_SHELL", "from shell")
+	writeFile(t, filepath.Join(baseDir, ".env"), "REACT_APP_FILE=file value\nREACT_APP_LOCAL=env\nREACT_APP_SHELL=ignored")
+	writeFile(t, filepath.Join(baseDir, ".env.local"), "REACT_APP_LOCAL=local")
+
+	if _, err := loadEnvFiles("development"); err != nil {
+		t.Fatalf("loadEnvFiles() returned error: %v", err)
+	}
+	if _, err := buildDefinedReplacements(*config, "development", false); err != nil {
+		t.Fatalf("buildDefinedReplacements() returned error: %v", err)
+	}
+
+	got := make(map[string]envDefine)
+	for _, define := range envDefines(true) {
+		got[define.Key] = define
+	}
+	for key, want := range map[string]envDefine{
+		"process.env.REACT_APP_FILE":      {Value: []byte(`"fi****"`), Source: ".env"},
+		"import.meta.env.REACT_APP_LOCAL": {Value: []byte(`"****"`), Source: ".env.local"},
+		"process.env.REACT_APP_SHELL":     {Value: []byte(`"fr****"`), Source: "environment"},
+		"process.env.NODE_ENV":            {Value: []byte(`"development"`), Source: "nrb"},
+	} {
+		if string(got[key].Value) != string(want.Value) || got[key].Source != want.Source {
+			t.Fatalf("define %s = %s (%s), want %s (%s)", key, got[key].Value, got[key].Source, want.Value, want.Source)
+		}
+	}
+	if metaEnv := string(got["import.meta.env"].Value); strings.Contains(metaEnv, "file value") || !strings.Contains(metaEnv, `"MODE":"development"`) {
+		t.Fatalf("import.meta.env = %s, want masked env values and plain nrb values", metaEnv)
+	}
+}
+
+func TestEnvCommandListsDefinesWhenSchemaFailsInBuildMode(t *testing.T) {
+	resetRuntimeBridgeState()
+	t.Cleanup(resetRuntimeBridgeState)
+	for _, name := range []string{"REACT_APP_API", "REACT_APP_TITLE", "NODE_ENV"} {
+		t.Setenv(name, "")
+		_ = os.Unsetenv(name)
+	}
+	t.Setenv("REACT_APP_TITLE", "app")
+
+	baseDir = t.TempDir()
+	writePackageJSON(t, baseDir, `{"name": "app", "nrb": {"env": {"REACT_APP_API": {"type": "url", "required": true}}}}`)
+
+	// build mode would exit here, env command has to show defines first
+	err := envCommand([]string{"-mode=production"})
+	if err == nil || !strings.Contains(err.Error(), "'env' schema") {
+		t.Fatalf("envCommand() = %v, want env schema error", err)
+	}
+	if got := definedReplacements["process.env.REACT_APP_TITLE"]; got != `"app"` {
+		t.Fatalf("process.env.REACT_APP_TITLE = %s, want defines prepared despite schema problems", got)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/natrim/nrb/lib"
+	"github.com/natrim/nrb/lib/plugins"
+)
+
+var hmrEnabled bool
+
+type hmrUpdate struct {
+	Path string `json:"path"`
+	Code string `json:"code"`
+}
+
+// setupHMR adds hmr plugin to watch build if enabled and react-refresh is installed
+func setupHMR() {
+	hmrEnabled = false
+	defer func() {
+		if buildOptions.Define != nil {
+			buildOptions.Define["process.env.FAST_REFRESH"] = strconv.FormatBool(hmrEnabled)
+		}
+	}()
+	if !config.HMR {
+		return
+	}
+
+	if !lib.FileExists(filepath.Join(baseDir, "node_modules", "react-refresh", "runtime.js")) {
+		lib.PrintWarn("hmr needs 'react-refresh' package installed, using page reload")
+		return
+	}
+
+	hmrEnabled = true
+	buildOptions.Plugins = append(buildOptions.Plugins, plugins.HMRPlugin(config.SourceDir, entryPoints()))
+}
+
+// isHMRChange checks if all changed files are scripts that can be hot updated
+func isHMRChange(names []string) bool {
+	if !hmrEnabled || len(names) == 0 {
+		return false
+	}
+	for _, name := range names {
+		switch filepath.Ext(name) {
+		case ".tsx", ".jsx", ".ts", ".js":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// buildHMRUpdates bundles every changed file on its own, imports of the file are taken from modules already loaded in browser
+func buildHMRUpdates(sourceDir string, names []string) ([]hmrUpdate, error) {
+	updates := make([]hmrUpdate, 0, len(names))
+	for _, name := range names {
+		options := buildOptions
+		options.EntryPoints = []string{plugins.HMRUpdateEntry}
+		options.Banner = nil
+		options.Splitting = false
+		options.Metafile = false
+		options.Write = false
+		options.Sourcemap = api.SourceMapInline
+		options.Plugins = append(esbuildPlugins(), plugins.HMRUpdatePlugin(config.SourceDir, filepath.Join(sourceDir, name)))
+
+		result := api.Build(options)
+		if len(result.Errors) > 0 {
+			errs := make([]error, len(result.Errors))
+			for i, err := range result.Errors {
+				errs[i] = errors.New("-*- " + err.Text)
+			}
+			return nil, errors.Join(errs...)
+		}
+
+		for _, file := range result.OutputFiles {
+			if strings.HasSuffix(file.Path, ".js") {
+				updates = append(updates, hmrUpdate{Path: name, Code: string(file.Contents)})
+				break
+			}
+		}
+	}
+	return updates, nil
+}
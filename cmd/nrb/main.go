@@ -25,7 +25,17 @@ var packagePath = "package.json"
 var buildOptions api.BuildOptions
 
 var versionData = "dev"
+var appMode string
 var definedReplacements lib.MapFlags
+var interpolationVars lib.MapFlags
+
+// envSources are files env variables were loaded from, variables from environment are not there
+var envSources = make(map[string]string)
+
+// envDefaults are 'env' schema defaults of variables not set in environment, process environment is not changed,
+// so reload can drop or change them
+var envDefaults = make(map[string]string)
+var loadedEnvFiles []string
 
 func main() {
 	var err error
@@ -78,13 +88,40 @@ func main() {
 			lib.PrintError(err)
 			os.Exit(1)
 		}
+	case "env":
+		if err := refreshRuntimeConfig(true); err != nil {
+			lib.PrintError(err)
+			os.Exit(1)
+		}
+		if err := envCommand(flag.Args()[1:]); err != nil {
+			lib.PrintError(err)
+			os.Exit(1)
+		}
+	case "analyze":
+		if err := refreshRuntimeConfig(true); err != nil {
+			lib.PrintError(err)
+			os.Exit(1)
+		}
+		if err := analyzeCommand(flag.Args()[1:]); err != nil {
+			lib.PrintError(err)
+			os.Exit(1)
+		}
+	case "why":
+		if err := refreshRuntimeConfig(true); err != nil {
+			lib.PrintError(err)
+			os.Exit(1)
+		}
+		if err := whyCommand(flag.Args()[1:]); err != nil {
+			lib.PrintError(err)
+			os.Exit(1)
+		}
 	case "version":
 		lib.PrintInfo("NRB version is:", lib.Yellow(lib.Version))
 	default:
 		lib.PrintInfo("Usage:", lib.Blue(filepath.Base(os.Args[0])), "[flags]", lib.Yellow("command"))
 		lib.PrintInfof(
-			"use %s with '%s' to build the app, '%s' for watch mode, '%s' to serve build folder and '%s' to show this help\n",
-			lib.Yellow("command"), lib.Yellow("build"), lib.Yellow("watch"), lib.Yellow("serve"), lib.Yellow("help"),
+			"use %s with '%s' to build the app, '%s' for watch mode, '%s' to serve build folder, '%s' for env variables, '%s' for bundle treemap, '%s <module>' to explain why module is in bundle and '%s' to show this help\n",
+			lib.Yellow("command"), lib.Yellow("build"), lib.Yellow("watch"), lib.Yellow("serve"), lib.Yellow("env"), lib.Yellow("analyze"), lib.Yellow("why"), lib.Yellow("help"),
 		)
 		lib.Printe("Flags:")
 		flag.PrintDefaults()
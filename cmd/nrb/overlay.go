@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// overlayJS renders build errors from 'build' event over the running app, successful build removes it
+var overlayJS = "function ov(d){var o=document.getElementById(\"nrb-overlay\");if(o)o.remove();(d.warnings||[]).forEach(m=>console.warn(\"[nrb]\",(m.file?m.file+\":\"+m.line+\":\"+m.column+\" \":\"\")+m.text));if(!d.errors||!d.errors.length){if(window.nrbBroken)window.location.reload();return}o=document.createElement(\"div\");o.id=\"nrb-overlay\";o.style.cssText=\"position:fixed;inset:0;z-index:2147483647;background:rgba(0,0,0,.85);color:#eee;font:13px/1.5 monospace;overflow:auto;padding:24px\";var b=document.createElement(\"button\");b.textContent=\"\\u00d7\";b.title=\"dismiss\";b.style.cssText=\"position:absolute;top:8px;right:16px;font-size:24px;background:none;border:0;color:#eee;cursor:pointer\";b.onclick=()=>o.remove();o.appendChild(b);d.errors.concat(d.warnings||[]).forEach((m,i)=>{var p=document.createElement(\"pre\");p.style.cssText=\"white-space:pre-wrap;margin:0 0 16px;padding:12px;border-left:4px solid \"+(i<d.errors.length?\"#e55\":\"#eb3\");p.textContent=(m.file?m.file+\":\"+m.line+\":\"+m.column+\"\\n\":\"\")+m.text+(m.frame?\"\\n\\n\"+m.frame:\"\");o.appendChild(p)});document.body.appendChild(o)}"
+
+type buildMessage struct {
+	Text   string `json:"text"`
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+	Frame  string `json:"frame,omitempty"`
+}
+
+type buildReport struct {
+	Errors   []buildMessage `json:"errors"`
+	Warnings []buildMessage `json:"warnings"`
+}
+
+// makeBuildReport converts esbuild result messages to data for browser overlay
+func makeBuildReport(result api.BuildResult) buildReport {
+	return buildReport{
+		Errors:   makeBuildMessages(result.Errors),
+		Warnings: makeBuildMessages(result.Warnings),
+	}
+}
+
+func makeBuildMessages(messages []api.Message) []buildMessage {
+	out := make([]buildMessage, len(messages))
+	for i, msg := range messages {
+		out[i] = buildMessage{Text: msg.Text}
+		if msg.PluginName != "" {
+			out[i].Text = "[plugin " + msg.PluginName + "] " + msg.Text
+		}
+		if msg.Location != nil {
+			out[i].File = msg.Location.File
+			out[i].Line = msg.Location.Line
+			out[i].Column = msg.Location.Column
+			out[i].Frame = codeFrame(msg.Location)
+		}
+	}
+	return out
+}
+
+// codeFrame shows the line with error and marks the error position under it
+func codeFrame(loc *api.Location) string {
+	if loc == nil || loc.LineText == "" {
+		return ""
+	}
+
+	gutter := fmt.Sprintf("%d | ", loc.Line)
+	column := min(max(loc.Column, 0), len(loc.LineText))
+
+	// keep tabs so marker lines up with the text
+	indent := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, loc.LineText[:column])
+
+	return gutter + loc.LineText + "\n" +
+		strings.Repeat(" ", len(gutter)-2) + "| " + indent + "^" + strings.Repeat("~", max(loc.Length-1, 0))
+}
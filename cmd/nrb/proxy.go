@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/natrim/nrb/lib"
+)
+
+// hop-by-hop headers, these are not forwarded by proxies
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+var proxyClient = func() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// pass encoding from backend as is
+	transport.DisableCompression = true
+
+	return &http.Client{
+		Transport: transport,
+		// redirects are for the browser to follow
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}()
+
+// proxyMiddleware sends requests matching config proxy rules to backend before static/esbuild handlers
+func proxyMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if rule, ok := findProxyRule(request); ok {
+			pipeRequestToProxy(writer, request, rule)
+			return
+		}
+
+		next(writer, request)
+	}
+}
+
+func findProxyRule(r *http.Request) (lib.ProxyRule, bool) {
+	for _, rule := range config.Proxy {
+		if rule.Matches(r.URL.Path) {
+			return rule, true
+		}
+		if rule.Fallback && isFallbackProxyRequest(r) {
+			return rule, true
+		}
+	}
+	return lib.ProxyRule{}, false
+}
+
+// isFallbackProxyRequest mimics CRA, so only requests without text/html in Accept header which are not static files nor esbuild assets get proxied
+func isFallbackProxyRequest(r *http.Request) bool {
+	if r.URL.Path == "/" || strings.Contains(r.Header.Get("Accept"), "text/html") {
+		return false
+	}
+	if strings.HasPrefix(r.URL.Path, "/"+config.AssetsDir+"/") {
+		return false
+	}
+	if lib.FileExists(filepath.Join(config.StaticDir, filepath.FromSlash(r.URL.Path))) {
+		return false
+	}
+	return true
+}
+
+func pipeRequestToProxy(w http.ResponseWriter, r *http.Request, rule lib.ProxyRule) {
+	target, err := rule.TargetURL(r.URL.Path, r.URL.RawQuery)
+	if err != nil {
+		lib.PrintError(err)
+		http.Error(w, "502 - Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), r.Body)
+	if err != nil {
+		lib.PrintError(err)
+		http.Error(w, "502 - Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	req.ContentLength = r.ContentLength
+
+	for k, vv := range r.Header {
+		for _, v := range vv {
+			req.Header.Add(k, v)
+		}
+	}
+	removeHopHeaders(req.Header)
+	setXForwardedFrom(req, r)
+
+	// keep upgrade request for websockets
+	upgrade := upgradeType(r.Header)
+	if upgrade != "" {
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", upgrade)
+	}
+
+	if rule.ChangeOrigin {
+		req.Host = target.Host
+	} else {
+		req.Host = r.Host
+	}
+
+	for k, v := range rule.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := proxyClient.Do(req)
+	if err != nil {
+		lib.PrintError("proxy", r.URL.Path, "->", target.String(), err)
+		http.Error(w, "502 - Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		pipeUpgradedConnection(w, resp, upgrade)
+		return
+	}
+
+	removeHopHeaders(resp.Header)
+	for k, vv := range resp.Header {
+		for _, v := range vv {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	// flush streams (SSE, chunked responses) as they come, everything else gets copied buffered
+	streaming := resp.ContentLength == -1 || strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+	err = copyProxyResponse(w, resp.Body, streaming)
+	if err != nil && !errors.Is(err, syscall.EPIPE) && !errors.Is(err, context.Canceled) {
+		lib.PrintError(err)
+	}
+}
+
+// copyProxyResponse copies backend body to client, flushing after every read if streaming
+func copyProxyResponse(w http.ResponseWriter, body io.Reader, streaming bool) error {
+	if !streaming {
+		_, err := io.Copy(w, body)
+		return err
+	}
+
+	rc := http.NewResponseController(w)
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// pipeUpgradedConnection hijacks client connection and pipes it both ways with upgraded backend connection
+func pipeUpgradedConnection(w http.ResponseWriter, resp *http.Response, upgrade string) {
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), upgrade) {
+		lib.PrintError("proxy backend switched to unrequested protocol", resp.Header.Get("Upgrade"))
+		http.Error(w, "502 - Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	backendConn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		lib.PrintError("proxy backend connection cannot be upgraded")
+		http.Error(w, "502 - Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	clientConn, clientBuf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		lib.PrintError("proxy cannot upgrade client connection", err)
+		http.Error(w, "502 - Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = clientConn.Close() }()
+
+	removeHopHeaders(resp.Header)
+	resp.Header.Set("Connection", "Upgrade")
+	resp.Header.Set("Upgrade", upgrade)
+
+	_, _ = fmt.Fprintf(clientBuf, "HTTP/1.1 %d %s\r\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+	_ = resp.Header.Write(clientBuf)
+	_, _ = clientBuf.WriteString("\r\n")
+	if err := clientBuf.Flush(); err != nil {
+		lib.PrintError(err)
+		return
+	}
+
+	// first side to finish closes both
+	errc := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(backendConn, clientBuf)
+		errc <- err
+	}()
+	go func() {
+		_, err := io.Copy(clientConn, backendConn)
+		errc <- err
+	}()
+	<-errc
+}
+
+// upgradeType returns requested protocol if request wants connection upgrade
+func upgradeType(h http.Header) string {
+	for _, f := range h.Values("Connection") {
+		for sf := range strings.SplitSeq(f, ",") {
+			if strings.EqualFold(strings.TrimSpace(sf), "upgrade") {
+				return h.Get("Upgrade")
+			}
+		}
+	}
+	return ""
+}
+
+func removeHopHeaders(h http.Header) {
+	// headers listed in Connection are hop-by-hop too
+	for _, f := range h.Values("Connection") {
+		for sf := range strings.SplitSeq(f, ",") {
+			if sf = strings.TrimSpace(sf); sf != "" {
+				h.Del(sf)
+			}
+		}
+	}
+	for _, k := range hopHeaders {
+		h.Del(k)
+	}
+}
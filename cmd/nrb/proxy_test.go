@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/natrim/nrb/lib"
+)
+
+func TestProxyMiddlewarePipesMatchingRequestsToBackend(t *testing.T) {
+	t.Cleanup(func() {
+		resetRuntimeBridgeState()
+	})
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend-Path", r.URL.RequestURI())
+		w.Header().Set("X-Backend-Host", r.Host)
+		w.Header().Set("X-Backend-Dev", r.Header.Get("X-Dev"))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.Copy(w, r.Body)
+	}))
+	defer backend.Close()
+
+	resetRuntimeBridgeState()
+	config.StaticDir = t.TempDir()
+	config.Proxy = []lib.ProxyRule{{
+		Path:         "/api",
+		Target:       backend.URL,
+		PathRewrite:  map[string]string{"^/api": "/v1"},
+		Headers:      map[string]string{"X-Dev": "yes"},
+		ChangeOrigin: true,
+	}}
+
+	nextCalled := false
+	handler := proxyMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		nextCalled = true
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:3000/api/users?page=2", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if nextCalled {
+		t.Fatal("did not expect proxied request to reach next handler")
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected backend status %d, got %d", http.StatusCreated, rec.Code)
+	}
+	if got := rec.Header().Get("X-Backend-Path"); got != "/v1/users?page=2" {
+		t.Fatalf("expected rewritten path %q, got %q", "/v1/users?page=2", got)
+	}
+	if got := rec.Header().Get("X-Backend-Host"); got != backend.Listener.Addr().String() {
+		t.Fatalf("expected changed origin host %q, got %q", backend.Listener.Addr().String(), got)
+	}
+	if got := rec.Header().Get("X-Backend-Dev"); got != "yes" {
+		t.Fatalf("expected injected header %q, got %q", "yes", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost:3000/assets/index.js", nil)
+	handler(httptest.NewRecorder(), req)
+	if !nextCalled {
+		t.Fatal("expected non matching request to reach next handler")
+	}
+}
+
+func TestProxyMiddlewareFallbackSkipsHTMLAndAssets(t *testing.T) {
+	t.Cleanup(func() {
+		resetRuntimeBridgeState()
+	})
+
+	resetRuntimeBridgeState()
+	config.StaticDir = t.TempDir()
+	config.Proxy = []lib.ProxyRule{{Path: "/", Target: "http://localhost:1", Fallback: true}}
+
+	htmlReq := httptest.NewRequest(http.MethodGet, "http://localhost:3000/todos", nil)
+	htmlReq.Header.Set("Accept", "text/html,application/xhtml+xml")
+	if _, ok := findProxyRule(htmlReq); ok {
+		t.Fatal("did not expect html navigation to be proxied")
+	}
+
+	assetReq := httptest.NewRequest(http.MethodGet, "http://localhost:3000/assets/index.js", nil)
+	if _, ok := findProxyRule(assetReq); ok {
+		t.Fatal("did not expect esbuild asset to be proxied")
+	}
+
+	apiReq := httptest.NewRequest(http.MethodGet, "http://localhost:3000/todos", nil)
+	apiReq.Header.Set("Accept", "application/json")
+	if _, ok := findProxyRule(apiReq); !ok {
+		t.Fatal("expected api request to be proxied by fallback rule")
+	}
+}
+
+func TestProxyMiddlewarePipesWebSocketUpgradeOverTLS(t *testing.T) {
+	t.Cleanup(func() {
+		resetRuntimeBridgeState()
+	})
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if upgradeType(r.Header) != "websocket" {
+			http.Error(w, "expected upgrade", http.StatusBadRequest)
+			return
+		}
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		_ = buf.Flush()
+		// echo one line back
+		line, err := buf.ReadString('\n')
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("echo:" + line))
+	}))
+	defer backend.Close()
+
+	resetRuntimeBridgeState()
+	config.StaticDir = t.TempDir()
+	config.Proxy = []lib.ProxyRule{{Path: "/ws", Target: backend.URL}}
+
+	front := httptest.NewTLSServer(proxyMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		error404(w, true)
+	}))
+	defer front.Close()
+
+	conn, err := tls.Dial("tcp", front.Listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("failed to dial front server: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, _ = conn.Write([]byte("GET /ws/live HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("failed to read upgrade response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Upgrade"); got != "websocket" {
+		t.Fatalf("expected upgrade header %q, got %q", "websocket", got)
+	}
+
+	_, _ = conn.Write([]byte("hello\n"))
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read piped data: %v", err)
+	}
+	if line != "echo:hello\n" {
+		t.Fatalf("expected echoed line %q, got %q", "echo:hello\n", line)
+	}
+}
+
+func TestProxyMiddlewareFlushesEventStreams(t *testing.T) {
+	t.Cleanup(func() {
+		resetRuntimeBridgeState()
+	})
+
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: first\n\n"))
+		_ = http.NewResponseController(w).Flush()
+		<-release
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	resetRuntimeBridgeState()
+	config.StaticDir = t.TempDir()
+	config.Proxy = []lib.ProxyRule{{Path: "/events", Target: backend.URL}}
+
+	front := httptest.NewServer(proxyMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		error404(w, true)
+	}))
+	defer front.Close()
+
+	resp, err := http.Get(front.URL + "/events")
+	if err != nil {
+		t.Fatalf("failed to request event stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	lineCh := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		lineCh <- line
+	}()
+
+	select {
+	case line := <-lineCh:
+		if line != "data: first\n" {
+			t.Fatalf("expected first event line, got %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not flushed through proxy")
+	}
+}
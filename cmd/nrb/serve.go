@@ -14,6 +14,11 @@ func serve() error {
 	fileServer := lib.WrappedFileServer(config.OutputDir)
 	http.Handle("/", fileServer)
 
+	return listenAndServe()
+}
+
+// listenAndServe serves registered handlers on configured host and port, https if certificates were found
+func listenAndServe() error {
 	socket, err := net.Listen("tcp", fmt.Sprintf("%s:%d", config.Host, config.Port))
 	if err != nil {
 		return err
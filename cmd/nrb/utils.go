@@ -8,10 +8,12 @@ import (
 	"mime"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/evanw/esbuild/pkg/api"
-	"github.com/joho/godotenv"
 	"github.com/natrim/nrb/lib"
 	"github.com/natrim/nrb/lib/plugins"
 )
@@ -21,6 +23,7 @@ type CLIState struct {
 	IsVersion bool
 	UseColor  bool
 	EnvFiles  string
+	Mode      string
 }
 
 func ParseFlags() (CLIState, lib.ConfigOverrides, error) {
@@ -38,6 +41,7 @@ func ParseFlags() (CLIState, lib.ConfigOverrides, error) {
 	isHelpFlag := false
 	useColorFlag := true
 	envFilesFlag := ""
+	modeFlag := ""
 
 	envPrefixFlag := defaults.EnvPrefix
 	sourceDirFlag := defaults.SourceDir
@@ -60,25 +64,37 @@ func ParseFlags() (CLIState, lib.ConfigOverrides, error) {
 	legalCommentsFlag := lib.LegalCommentsString(defaults.LegalComments)
 	sourceMapFlag := lib.SourceMapString(defaults.SourceMap)
 	splittingFlag := defaults.Splitting
+	hmrFlag := defaults.HMR
+	autoPreloadFlag := defaults.AutoPreload
+	prefetchFlag := defaults.Prefetch
+	sriFlag := defaults.SRI
+	cspFlag := defaults.CSP
+	cspModeFlag := defaults.CSPMode
+	htmlTemplateFlag := defaults.HTMLTemplate
+	envTypesFlag := defaults.EnvTypes
 	generateMetafileFlag := defaults.Metafile
 	tsConfigPathFlag := defaults.TSConfigPath
 	var preloadFlag lib.ArrayFlags
 	var resolveFlag lib.MapFlags
 	var aliasFlag lib.MapFlags
 	var injectFlag lib.ArrayFlags
+	var interpolateFlag lib.ArrayFlags
 	var inlineFlag lib.ArrayFlags
 	inlineSizeFlag := defaults.InlineSize
 	var loadersFlag lib.LoaderFlags
+	var proxyFlag lib.MapFlags
 
 	flag.BoolVar(&isVersionFlag, "version", isVersionFlag, "nrb version number")
 	flag.BoolVar(&isVersionFlag, "v", isVersionFlag, "alias of -version")
 	flag.BoolVar(&isHelpFlag, "h", isHelpFlag, "alias of -help")
 	flag.BoolVar(&isHelpFlag, "help", isHelpFlag, "this help")
-	flag.StringVar(&envFilesFlag, "env", envFilesFlag, "env files to load from (always loads .env first)")
+	flag.StringVar(&envFilesFlag, "env", envFilesFlag, "extra env files to load after .env, .env.local, .env.[mode] and .env.[mode].local")
+	flag.StringVar(&modeFlag, "mode", modeFlag, "env mode picking .env.[mode] files and import.meta.env.MODE, defaults to NODE_ENV, else development in watch and production in build")
 
 	flag.BoolVar(&useColorFlag, "color", useColorFlag, "colorize output")
 
 	flag.StringVar(&envPrefixFlag, "envPrefix", envPrefixFlag, "env variables prefix")
+	flag.StringVar(&envTypesFlag, "envTypes", envTypesFlag, "typescript declarations file of env variables to write on build and watch, ie. --envTypes=src/nrb-env.d.ts")
 	flag.StringVar(&sourceDirFlag, "sourceDir", sourceDirFlag, "source directory name")
 	flag.StringVar(&entryFileNameFlag, "entryFileName", entryFileNameFlag, "entry file name in 'sourceDir'")
 	flag.StringVar(&outputDirFlag, "outputDir", outputDirFlag, "output dir name")
@@ -103,19 +119,29 @@ func ParseFlags() (CLIState, lib.ConfigOverrides, error) {
 	flag.StringVar(&sourceMapFlag, "sourceMap", sourceMapFlag, "what sourcemap to use, available options: none|inline|linked|external|both")
 	flag.BoolVar(&splittingFlag, "splitting", splittingFlag, "enable code splitting")
 	flag.BoolVar(&splittingFlag, "split", splittingFlag, "alias of -splitting")
+	flag.BoolVar(&hmrFlag, "hmr", hmrFlag, "enable hot module replacement with react fast refresh in watch mode, needs 'react-refresh' package")
+	flag.BoolVar(&autoPreloadFlag, "autoPreload", autoPreloadFlag, "module=preload on build all chunks the entry statically imports")
+	flag.BoolVar(&prefetchFlag, "prefetch", prefetchFlag, "prefetch on build chunks the entry imports dynamically")
+	flag.BoolVar(&sriFlag, "sri", sriFlag, "add subresource integrity hashes to js/css tags in index.html on build")
+	flag.StringVar(&cspFlag, "csp", cspFlag, "content security policy, inline scripts/styles get hashed on build and nonce in watch, ie. --csp=\"default-src 'self'\"")
+	flag.StringVar(&cspModeFlag, "cspMode", cspModeFlag, "where to put content security policy on build, available options: meta|headers")
+	flag.BoolVar(&htmlTemplateFlag, "htmlTemplate", htmlTemplateFlag, "render index.html as go text/template with config, mode, version, env and chunks")
 
 	flag.Var(&preloadFlag, "preload", "paths to module=preload on build, overrides values from package.json, can have multiple flags, ie. --preload=src/index,node_modules/react")
 	flag.Var(&resolveFlag, "resolve", "resolve package import with 'package:path', overrides values from package.json, can have multiple flags, ie. --resolve=react:packages/super-react/index.js,redux:node_modules/redax/lib/index.js")
 	flag.Var(&aliasFlag, "alias", "alias package with another 'package:aliasedpackage', overrides values from package.json, can have multiple flags, ie. --alias=react:preact-compat,react-dom:preact-compat")
 	flag.Var(&injectFlag, "inject", "allows you to automatically replace a global variable with an import from another file, overrides values from package.json, can have multiple flags, ie. --inject=./process-shim.js,./react-shim.js")
 
+	flag.Var(&interpolateFlag, "interpolate", "static files to replace %PUBLIC_URL%, %NODE_ENV% and prefixed env variables in, overrides values from package.json, ie. --interpolate=manifest.json,robots.txt")
+
 	flag.Var(&inlineFlag, "inline", "file extensions to inline as base64 dataurls, overrides values from package.json, ie. --inline=png,jpg,svg")
 	flag.Int64Var(&inlineSizeFlag, "inlineSize", inlineSizeFlag, "set max file size to inline as base64 dataurls as int in bytes, default is 0 which inlines ALL, overrides values from package.json, ie. for 10kb set --inlineSize=10000")
 
-	flag.BoolVar(&generateMetafileFlag, "metafile", generateMetafileFlag, "generate metafile for bundle analysis, ie. on https://esbuild.github.io/analyze/")
+	flag.BoolVar(&generateMetafileFlag, "metafile", generateMetafileFlag, "save 'build-meta.json' metafile for bundle analysis, ie. with 'analyze' command")
 	flag.StringVar(&tsConfigPathFlag, "tsconfig", tsConfigPathFlag, "path to tsconfig json, relative to current work directory")
 
 	flag.Var(&loadersFlag, "loaders", "esbuild file loaders, overrides values from package.json, ie. --loaders=png:dataurl,.txt:copy,data:json")
+	flag.Var(&proxyFlag, "proxy", "proxy watch server requests starting with path to backend 'path:target', overrides values from package.json, can have multiple flags, ie. --proxy=/api:http://localhost:8080")
 
 	// parse flags
 	err := flag.CommandLine.Parse(os.Args[1:])
@@ -129,13 +155,14 @@ func ParseFlags() (CLIState, lib.ConfigOverrides, error) {
 		IsVersion: isVersionFlag,
 		UseColor:  useColorFlag,
 		EnvFiles:  envFilesFlag,
+		Mode:      modeFlag,
 	}
 
 	// set color output before any output
 	lib.UseColor(state.UseColor)
 
-	// handle too many arguments, only flags and one command allowed
-	if flag.NArg() > 1 {
+	// handle too many arguments, only flags and one command allowed, some commands have their own
+	if flag.NArg() > 1 && !commandsWithArgs[flag.Arg(0)] {
 		lib.PrintError("use flags before", lib.Yellow("command"))
 		lib.PrintInfo("Usage:", lib.Blue(filepath.Base(os.Args[0])), "[flags]", lib.Yellow("command"))
 		return state, overrides, errors.New("too many arguments, only one command allowed")
@@ -227,6 +254,35 @@ func ParseFlags() (CLIState, lib.ConfigOverrides, error) {
 	if passedFlags["splitting"] || passedFlags["split"] {
 		overrides.Splitting = lib.OptionalBool{Value: splittingFlag, Set: true}
 	}
+	if passedFlags["hmr"] {
+		overrides.HMR = lib.OptionalBool{Value: hmrFlag, Set: true}
+	}
+	if passedFlags["autoPreload"] {
+		overrides.AutoPreload = lib.OptionalBool{Value: autoPreloadFlag, Set: true}
+	}
+	if passedFlags["prefetch"] {
+		overrides.Prefetch = lib.OptionalBool{Value: prefetchFlag, Set: true}
+	}
+	if passedFlags["sri"] {
+		overrides.SRI = lib.OptionalBool{Value: sriFlag, Set: true}
+	}
+	if passedFlags["csp"] {
+		overrides.CSP = lib.OptionalString{Value: cspFlag, Set: true}
+	}
+	if passedFlags["cspMode"] {
+		cspMode, err := lib.ParseCSPMode(cspModeFlag)
+		if err != nil {
+			lib.Printe(err)
+			os.Exit(1)
+		}
+		overrides.CSPMode = lib.OptionalString{Value: cspMode, Set: true}
+	}
+	if passedFlags["envTypes"] {
+		overrides.EnvTypes = lib.OptionalString{Value: envTypesFlag, Set: true}
+	}
+	if passedFlags["htmlTemplate"] {
+		overrides.HTMLTemplate = lib.OptionalBool{Value: htmlTemplateFlag, Set: true}
+	}
 	if passedFlags["alias"] {
 		overrides.AliasPackages = aliasFlag
 	}
@@ -239,6 +295,13 @@ func ParseFlags() (CLIState, lib.ConfigOverrides, error) {
 	if passedFlags["inject"] {
 		overrides.Injects = injectFlag
 	}
+	if passedFlags["interpolate"] {
+		if err := lib.CheckGlobs(interpolateFlag); err != nil {
+			lib.Printe(err)
+			os.Exit(1)
+		}
+		overrides.Interpolate = interpolateFlag
+	}
 	if passedFlags["inline"] {
 		overrides.InlineExtensions = inlineFlag
 	}
@@ -248,10 +311,25 @@ func ParseFlags() (CLIState, lib.ConfigOverrides, error) {
 	if passedFlags["loaders"] {
 		overrides.Loaders = loadersFlag
 	}
+	if passedFlags["proxy"] {
+		proxyRules, err := lib.ParseProxyFlags(proxyFlag)
+		if err != nil {
+			lib.Printe(err)
+			os.Exit(1)
+		}
+		overrides.Proxy = proxyRules
+	}
 
 	return state, overrides, nil
 }
 
+// commandsWithArgs are commands parsing arguments after them
+var commandsWithArgs = map[string]bool{
+	"env":     true,
+	"analyze": true,
+	"why":     true,
+}
+
 func collectPassedFlags(flagSet *flag.FlagSet) map[string]bool {
 	passedFlags := make(map[string]bool)
 	flagSet.Visit(func(f *flag.Flag) {
@@ -295,6 +373,19 @@ func normalizeRuntimeConfig(cfg lib.Config) lib.Config {
 	if cfg.StaticDir != "" {
 		cfg.StaticDir = filepath.Join(baseDir, cfg.StaticDir)
 	}
+	if cfg.EnvTypes != "" {
+		cfg.EnvTypes = filepath.Join(baseDir, cfg.EnvTypes)
+	}
+	if cfg.Pages != nil {
+		pages := make([]lib.Page, len(cfg.Pages))
+		for i, page := range cfg.Pages {
+			if page.Template != "" {
+				page.Template = filepath.Join(baseDir, page.Template)
+			}
+			pages[i] = page
+		}
+		cfg.Pages = pages
+	}
 
 	if cfg.SourceDir == "" {
 		cfg.SourceDir = "."
@@ -350,88 +441,244 @@ func SetupWebServer() {
 	}
 }
 
-func resolveEnvFiles() string {
-	envPaths := strings.Join(strings.Fields(strings.Trim(cliState.EnvFiles, ",")), "")
-	if lib.FileExists(filepath.Join(baseDir, ".env")) {
-		if envPaths != "" {
-			return ".env," + envPaths
+// resolveMode returns env mode, --mode flag wins, then NODE_ENV, else development in watch and production in build
+func resolveMode(isBuildMode bool) string {
+	if cliState.Mode != "" {
+		return cliState.Mode
+	}
+	if nodeEnv := os.Getenv("NODE_ENV"); nodeEnv != "" {
+		return nodeEnv
+	}
+	if isBuildMode {
+		return "production"
+	}
+	return "development"
+}
+
+// envFile is env file to load, Name is shown in output and sources, Path is where it is read from
+type envFile struct {
+	Name string
+	Path string
+}
+
+// resolveEnvFiles returns existing env files of mode cascade in base dir and files passed with -env as given, later files take precedence
+func resolveEnvFiles(mode string) []envFile {
+	cascade := []string{".env", ".env.local", ".env." + mode, ".env." + mode + ".local"}
+	if mode == "test" {
+		// tests should give same results for everyone
+		cascade = []string{".env", ".env." + mode}
+	}
+
+	var envFiles []envFile
+	for _, name := range cascade {
+		if envPath := filepath.Join(baseDir, name); lib.FileExists(envPath) {
+			envFiles = append(envFiles, envFile{Name: name, Path: envPath})
 		}
-		return ".env"
 	}
-	return envPaths
+	for name := range strings.SplitSeq(cliState.EnvFiles, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.ContainsFunc(envFiles, func(f envFile) bool { return f.Path == filepath.Clean(name) }) {
+			continue
+		}
+		envFiles = append(envFiles, envFile{Name: name, Path: name})
+	}
+	return envFiles
 }
 
-func loadEnvFiles() (string, error) {
-	envPaths := resolveEnvFiles()
-	if envPaths == "" {
-		return "", nil
+// loadEnvFiles loads env files of mode, variables already set in environment are kept, envSources get file of every variable
+func loadEnvFiles(mode string) ([]string, error) {
+	var envNames []string
+	values := make(map[string]string)
+	sources := make(map[string]string)
+	for _, file := range resolveEnvFiles(mode) {
+		contents, err := os.ReadFile(file.Path)
+		var keys []string
+		if err == nil {
+			keys, err = lib.ParseEnv(contents, values, os.LookupEnv)
+		}
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("cannot load %s file", file.Name), err)
+		}
+		for _, key := range keys {
+			sources[key] = file.Name
+		}
+		envNames = append(envNames, file.Name)
 	}
 
-	if err := godotenv.Overload(strings.Split(envPaths, ",")...); err != nil {
-		return "", errors.Join(errors.New("cannot load .env file/s"), err)
+	for name, value := range values {
+		if _, ok := os.LookupEnv(name); !ok {
+			_ = os.Setenv(name, value)
+			envSources[name] = sources[name]
+		}
 	}
 
-	return envPaths, nil
+	return envNames, nil
 }
 
-func buildDefinedReplacements(cfg lib.Config, isBuildMode bool) string {
-	var MODE = os.Getenv("NODE_ENV")
-	if MODE == "" && !isBuildMode {
-		MODE = "development"
-	} else if MODE == "" && isBuildMode {
-		MODE = "production"
+func buildDefinedReplacements(cfg lib.Config, mode string, isBuildMode bool) (string, error) {
+	// NODE_ENV follows standard modes, custom ones like staging get command default
+	nodeEnv, _ := lookupEnv("NODE_ENV")
+	if nodeEnv == "" {
+		switch {
+		case mode == "development" || mode == "production" || mode == "test":
+			nodeEnv = mode
+		case isBuildMode:
+			nodeEnv = "production"
+		default:
+			nodeEnv = "development"
+		}
 	}
 
-	isDevelopment := "false"
-	isProduction := "false"
-	if MODE == "development" {
-		isDevelopment = "true"
-		isProduction = "false"
-	} else {
-		isDevelopment = "false"
-		isProduction = "true"
-	}
+	isDevelopment := nodeEnv == "development"
+	publicURL := strings.TrimSuffix(cfg.PublicURL, "/")
 
 	define := map[string]string{
+		// cra fallback, setupHMR turns it on when hmr plugin is used
+		"process.env.FAST_REFRESH": "false",
+
+		// import.meta stuff
+		"import.meta.env.PROD": strconv.FormatBool(!isDevelopment),
+		"import.meta.env.DEV":  strconv.FormatBool(isDevelopment),
+		"import.meta.env.SSR":  "false",
+	}
+
+	// string values, encoded as js strings below
+	stringDefines := map[string]string{
 		// libs fallback
-		"process.env.NODE_ENV": fmt.Sprintf("\"%s\"", MODE),
+		"process.env.NODE_ENV": nodeEnv,
 
 		// cra fallback
-		"process.env.FAST_REFRESH": "false",
-		"process.env.PUBLIC_URL":   fmt.Sprintf("\"%s\"", strings.TrimSuffix(cfg.PublicURL, "/")),
+		"process.env.PUBLIC_URL": publicURL,
 
 		// import.meta stuff
-		"import.meta.env.MODE":     fmt.Sprintf("\"%s\"", MODE),
-		"import.meta.env.BASE_URL": fmt.Sprintf("\"%s\"", strings.TrimSuffix(cfg.PublicURL, "/")),
-		"import.meta.env.PROD":     isProduction,
-		"import.meta.env.DEV":      isDevelopment,
+		"import.meta.env.MODE":     mode,
+		"import.meta.env.BASE_URL": publicURL,
+	}
 
-		// metaData version
-		"process.env." + cfg.EnvPrefix + "VERSION": fmt.Sprintf("\"%v\"", "\"dev\""),
-		"import.meta." + cfg.EnvPrefix + "VERSION": fmt.Sprintf("\"%v\"", "\"dev\""),
+	// whole import.meta.env for Object.keys and such, single properties above get inlined
+	metaEnv := map[string]any{
+		"MODE":     mode,
+		"BASE_URL": publicURL,
+		"PROD":     !isDevelopment,
+		"DEV":      isDevelopment,
+		"SSR":      false,
 	}
 
-	envAll := os.Environ()
-	for _, v := range envAll {
-		env := strings.SplitN(v, "=", 2)
-		if strings.HasPrefix(env[0], cfg.EnvPrefix) {
-			define[fmt.Sprintf("process.env.%s", env[0])] = fmt.Sprintf("\"%s\"", env[1])
-			define[fmt.Sprintf("import.meta.%s", env[0])] = fmt.Sprintf("\"%s\"", env[1])
+	// %NAME% values for index.html and interpolated static files
+	vars := lib.MapFlags{
+		"NODE_ENV":   nodeEnv,
+		"PUBLIC_URL": publicURL,
+	}
+
+	envs := prefixedEnv(cfg.EnvPrefix)
+	// metaData version
+	envs[cfg.EnvPrefix+"VERSION"] = versionData
+
+	for name, value := range envs {
+		stringDefines[fmt.Sprintf("process.env.%s", name)] = value
+		stringDefines[fmt.Sprintf("import.meta.%s", name)] = value
+		stringDefines[fmt.Sprintf("import.meta.env.%s", name)] = value
+		metaEnv[name] = value
+		vars[name] = value
+	}
+
+	for key, value := range stringDefines {
+		encoded, err := defineString(value)
+		if err != nil {
+			return "", fmt.Errorf("cannot define '%s': %w", key, err)
 		}
+		define[key] = encoded
 	}
 
+	encodedEnv, err := json.Marshal(metaEnv)
+	if err != nil {
+		return "", fmt.Errorf("cannot define 'import.meta.env': %w", err)
+	}
+	define["import.meta.env"] = string(encodedEnv)
+
 	// fallback missing
 	define["process.env"] = "{}"
 	define["import.meta"] = "{}"
 
 	definedReplacements = define
+	interpolationVars = vars
+
+	return nodeEnv, nil
+}
+
+// defineString encodes value as js string for esbuild define
+func defineString(value string) (string, error) {
+	if !utf8.ValidString(value) {
+		return "", errors.New("value is not valid utf-8")
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
 
-	return MODE
+// prefixedEnv returns env variables and 'env' schema defaults starting with prefix
+func prefixedEnv(prefix string) map[string]string {
+	envs := make(map[string]string)
+	for name, value := range envDefaults {
+		if strings.HasPrefix(name, prefix) {
+			envs[name] = value
+		}
+	}
+	for _, v := range os.Environ() {
+		env := strings.SplitN(v, "=", 2)
+		if strings.HasPrefix(env[0], prefix) {
+			envs[env[0]] = env[1]
+		}
+	}
+	return envs
 }
 
 var envLoaded bool
 
+// esbuildPlugins are plugins used by every esbuild build
+func esbuildPlugins() []api.Plugin {
+	return []api.Plugin{
+		plugins.AliasPlugin(config.ResolveModules),
+		plugins.InlinePlugin(config.InlineSize, config.InlineExtensions),
+	}
+}
+
+// entryPoints are entry files of all pages
+func entryPoints() []string {
+	pages := config.EntryPages()
+	entries := make([]string, len(pages))
+	for i, page := range pages {
+		entries[i] = filepath.Join(config.SourceDir, page.Entry)
+	}
+	return entries
+}
+
+// buildEsbuildConfig prepares esbuild build options, env variables not matching 'env' schema fail build mode and are warnings in watch
 func buildEsbuildConfig(isBuildMode bool) {
+	if problems := prepareEsbuildConfig(isBuildMode); len(problems) > 0 {
+		printEnvSchemaProblems(problems, isBuildMode)
+		if isBuildMode {
+			os.Exit(1)
+		}
+	}
+}
+
+// printEnvSchemaProblems lists env variables not matching 'env' schema as errors or warnings
+func printEnvSchemaProblems(problems []string, asErrors bool) {
+	if asErrors {
+		lib.PrintError("env variables do not match 'env' schema:")
+	} else {
+		lib.PrintWarn("env variables do not match 'env' schema:")
+	}
+	for _, problem := range problems {
+		lib.PrintItem(problem)
+	}
+}
+
+// prepareEsbuildConfig loads config and env and prepares esbuild build options, returns env variables not matching 'env' schema
+func prepareEsbuildConfig(isBuildMode bool) []string {
 	if err := refreshRuntimeConfig(true); err != nil {
 		lib.PrintError(err)
 		os.Exit(1)
@@ -439,23 +686,34 @@ func buildEsbuildConfig(isBuildMode bool) {
 
 	if !envLoaded {
 		envLoaded = true
+		appMode = resolveMode(isBuildMode)
 
-		env, err := loadEnvFiles()
+		env, err := loadEnvFiles(appMode)
 		if err != nil {
 			lib.PrintError(err)
 			os.Exit(1)
 		}
+		loadedEnvFiles = env
 
-		if env != "" {
-			lib.PrintInfof("env files: %s\n", env)
+		if len(env) > 0 {
+			lib.PrintInfof("env files: %s\n", strings.Join(env, ","))
 		}
 	}
 
-	mode := buildDefinedReplacements(*config, isBuildMode)
-	if mode != "" {
-		lib.PrintInfof("node mode: \"%s\"\n", mode)
+	if isBuildMode {
+		versionData = lib.ParseVersion()
+		lib.PrintInfo("app version:", versionData)
 	}
 
+	problems := applyEnvSchema()
+
+	nodeEnv, err := buildDefinedReplacements(*config, appMode, isBuildMode)
+	if err != nil {
+		lib.PrintError(err)
+		os.Exit(1)
+	}
+	lib.PrintInfof("mode: \"%s\", node env: \"%s\"\n", appMode, nodeEnv)
+
 	browserTarget := api.DefaultTarget
 	target := config.Target
 
@@ -488,16 +746,6 @@ func buildEsbuildConfig(isBuildMode bool) {
 		}
 	}
 
-	if isBuildMode {
-		versionData = lib.ParseVersion()
-		lib.PrintInfo("app version:", versionData)
-	}
-
-	if versionData != "" {
-		definedReplacements["process.env."+config.EnvPrefix+"VERSION"] = fmt.Sprintf("\"%v\"", versionData)
-		definedReplacements["import.meta."+config.EnvPrefix+"VERSION"] = fmt.Sprintf("\"%v\"", versionData)
-	}
-
 	apiColor := api.ColorIfTerminal
 	if !cliState.UseColor {
 		apiColor = api.ColorNever
@@ -506,7 +754,7 @@ func buildEsbuildConfig(isBuildMode bool) {
 	buildOptions = api.BuildOptions{
 		Color:             apiColor,
 		Target:            browserTarget,
-		EntryPoints:       []string{filepath.Join(config.SourceDir, config.EntryFileName)},
+		EntryPoints:       entryPoints(),
 		Outdir:            filepath.Join(config.OutputDir, config.AssetsDir),
 		PublicPath:        fmt.Sprintf("/%s/", config.AssetsDir), // change in index.html too, needs to be same as above
 		AssetNames:        config.AssetNames,
@@ -531,10 +779,7 @@ func buildEsbuildConfig(isBuildMode bool) {
 
 		Tsconfig: filepath.Join(baseDir, config.TSConfigPath),
 
-		Plugins: []api.Plugin{
-			plugins.AliasPlugin(config.ResolveModules),
-			plugins.InlinePlugin(config.InlineSize, config.InlineExtensions),
-		},
+		Plugins: esbuildPlugins(),
 
 		// react stuff
 		JSX:             config.JSX,
@@ -544,4 +789,6 @@ func buildEsbuildConfig(isBuildMode bool) {
 		JSXImportSource: config.JSXImportSource,
 		JSXSideEffects:  config.JSXSideEffects,
 	}
+
+	return problems
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/evanw/esbuild/pkg/api"
@@ -478,11 +481,17 @@ func resetRuntimeBridgeState() {
 	cfg := lib.DefaultConfig()
 	config = &cfg
 	configOverrides = lib.ConfigOverrides{}
+	cliState = CLIState{}
 	envLoaded = false
 	baseDir = "."
 	packagePath = "package.json"
 	versionData = "dev"
 	definedReplacements = nil
+	interpolationVars = nil
+	appMode = ""
+	envSources = make(map[string]string)
+	envDefaults = make(map[string]string)
+	loadedEnvFiles = nil
 	buildOptions = api.BuildOptions{}
 }
 
@@ -492,3 +501,144 @@ func writePackageJSON(t *testing.T, dir string, contents string) {
 		t.Fatalf("failed to write package.json: %v", err)
 	}
 }
+
+func TestLoadEnvFilesCascadesByMode(t *testing.T) {
+	t.Cleanup(resetRuntimeBridgeState)
+	resetRuntimeBridgeState()
+	baseDir = t.TempDir()
+
+	files := map[string]string{
+		".env":                  "A=env\nB=env\nC=env\nD=env\nE=env",
+		".env.local":            "B=local\nC=local\nD=local",
+		".env.staging":          "C=staging\nD=staging",
+		".env.staging.local":    "D=staging-local",
+		".env.test":             "C=test",
+		".env.test.local":       "D=test-local",
+		".env.other-mode.local": "A=other",
+	}
+	for name, contents := range files {
+		writeFile(t, filepath.Join(baseDir, name), contents)
+	}
+
+	unsetEnv := func(names ...string) {
+		for _, name := range names {
+			t.Setenv(name, "")
+			_ = os.Unsetenv(name)
+		}
+	}
+
+	unsetEnv("A", "B", "C", "D", "E")
+	t.Setenv("A", "process")
+	// -env paths are used as given, not joined with base dir
+	extraEnv := filepath.Join(t.TempDir(), "ci.env")
+	writeFile(t, extraEnv, "E=extra")
+	cliState.EnvFiles = extraEnv
+	loaded, err := loadEnvFiles("staging")
+	if err != nil {
+		t.Fatalf("loadEnvFiles() returned error: %v", err)
+	}
+	if want := []string{".env", ".env.local", ".env.staging", ".env.staging.local", extraEnv}; !slices.Equal(loaded, want) {
+		t.Fatalf("loadEnvFiles() = %v, want %v", loaded, want)
+	}
+	for name, want := range map[string]string{"A": "process", "B": "local", "C": "staging", "D": "staging-local", "E": "extra"} {
+		if got := os.Getenv(name); got != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// test mode skips .local files
+	unsetEnv("A", "B", "C", "D", "E")
+	cliState.EnvFiles = ""
+	if _, err = loadEnvFiles("test"); err != nil {
+		t.Fatalf("loadEnvFiles() returned error: %v", err)
+	}
+	for name, want := range map[string]string{"B": "env", "C": "test", "D": "env"} {
+		if got := os.Getenv(name); got != want {
+			t.Fatalf("test mode %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuildDefinedReplacementsSeparatesModeFromNodeEnv(t *testing.T) {
+	t.Cleanup(resetRuntimeBridgeState)
+	resetRuntimeBridgeState()
+	t.Setenv("NODE_ENV", "")
+	_ = os.Unsetenv("NODE_ENV")
+
+	cliState.Mode = "staging"
+	mode := resolveMode(true)
+	if mode != "staging" {
+		t.Fatalf("resolveMode() = %q, want %q", mode, "staging")
+	}
+
+	if nodeEnv, _ := buildDefinedReplacements(*config, mode, true); nodeEnv != "production" {
+		t.Fatalf("buildDefinedReplacements() = %q, want %q", nodeEnv, "production")
+	}
+	for key, want := range map[string]string{
+		"process.env.NODE_ENV": `"production"`,
+		"import.meta.env.MODE": `"staging"`,
+		"import.meta.env.PROD": "true",
+		"import.meta.env.DEV":  "false",
+	} {
+		if got := definedReplacements[key]; got != want {
+			t.Fatalf("definedReplacements[%q] = %q, want %q", key, got, want)
+		}
+	}
+
+	cliState.Mode = ""
+	if mode = resolveMode(false); mode != "development" {
+		t.Fatalf("resolveMode() = %q, want %q", mode, "development")
+	}
+	if nodeEnv, _ := buildDefinedReplacements(*config, "development", false); nodeEnv != "development" {
+		t.Fatalf("buildDefinedReplacements() = %q, want %q", nodeEnv, "development")
+	}
+}
+
+func TestBuildDefinedReplacementsEncodesValuesAsJSON(t *testing.T) {
+	t.Cleanup(resetRuntimeBridgeState)
+	resetRuntimeBridgeState()
+
+	t.Setenv("REACT_APP_QUOTED", "say \"hi\"\\\n</script>")
+	if _, err := buildDefinedReplacements(*config, "production", true); err != nil {
+		t.Fatalf("buildDefinedReplacements() returned error: %v", err)
+	}
+	if got, want := definedReplacements["process.env.REACT_APP_QUOTED"], `"say \"hi\"\\\n\u003c/script\u003e"`; got != want {
+		t.Fatalf("define = %s, want %s", got, want)
+	}
+
+	t.Setenv("REACT_APP_BROKEN", "\xff")
+	if _, err := buildDefinedReplacements(*config, "production", true); err == nil || !strings.Contains(err.Error(), "REACT_APP_BROKEN") {
+		t.Fatalf("buildDefinedReplacements() error = %v, want it to name REACT_APP_BROKEN", err)
+	}
+}
+
+func TestBuildDefinedReplacementsDefinesWholeImportMetaEnv(t *testing.T) {
+	t.Cleanup(resetRuntimeBridgeState)
+	resetRuntimeBridgeState()
+	t.Setenv("REACT_APP_API", "/api")
+
+	if _, err := buildDefinedReplacements(*config, "staging", true); err != nil {
+		t.Fatalf("buildDefinedReplacements() returned error: %v", err)
+	}
+
+	var metaEnv map[string]any
+	if err := json.Unmarshal([]byte(definedReplacements["import.meta.env"]), &metaEnv); err != nil {
+		t.Fatalf("import.meta.env define %q is not json object: %v", definedReplacements["import.meta.env"], err)
+	}
+	for key, want := range map[string]any{"MODE": "staging", "BASE_URL": "", "PROD": true, "DEV": false, "SSR": false, "REACT_APP_API": "/api", "REACT_APP_VERSION": "dev"} {
+		if metaEnv[key] != want {
+			t.Fatalf("import.meta.env.%s = %v, want %v", key, metaEnv[key], want)
+		}
+	}
+
+	for key, want := range map[string]string{
+		"import.meta.env.REACT_APP_API": `"/api"`,
+		"import.meta.REACT_APP_API":     `"/api"`,
+		"process.env.REACT_APP_API":     `"/api"`,
+		"import.meta.env.SSR":           "false",
+	} {
+		if got := definedReplacements[key]; got != want {
+			t.Fatalf("definedReplacements[%q] = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -1,18 +1,22 @@
 package main
 
 import (
-	"context"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"html"
 	"io/fs"
 	"net"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"slices"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 
 	"github.com/evanw/esbuild/pkg/api"
@@ -20,12 +24,11 @@ import (
 	"github.com/natrim/nrb/lib"
 )
 
-var proxyPort uint16
 var protocol string
 var broker *lib.Broker
 var changedNames []string
 
-var reloadJS = "(()=>{if(!window.nrbIn){window.nrbIn=1;var lim=0;function c(){var s=new EventSource(\"/esbuild\");s.onopen=()=>{lim=0};s.onerror=()=>{s.close();lim++;if(lim>=30)window.location.reload();else setTimeout(c,10000)};s.onmessage=()=>{s.close();window.location.reload()}}c()}})();"
+var reloadJS = "(()=>{if(!window.nrbIn){window.nrbIn=1;var lim=0;" + overlayJS + ";function c(){var s=new EventSource(\"/esbuild\");s.onopen=()=>{lim=0};s.onerror=()=>{s.close();lim++;if(lim>=30)window.location.reload();else setTimeout(c,10000)};s.onmessage=()=>{s.close();window.location.reload()};s.addEventListener(\"build\",e=>ov(JSON.parse(e.data)));s.addEventListener(\"css\",()=>{document.querySelectorAll(\"link[rel=stylesheet]\").forEach(l=>{var u=new URL(l.href);if(u.origin!==location.origin)return;u.searchParams.set(\"nrb\",Date.now());var n=l.cloneNode();n.href=u.href;n.onload=n.onerror=()=>l.remove();l.after(n)})});s.addEventListener(\"hmr\",e=>{var h=window.__nrbHmr;(h?h.apply(e.data):Promise.reject()).catch(()=>{s.close();window.location.reload()})})}c()}})();"
 
 func watch() error {
 	// setup web server vars
@@ -34,14 +37,20 @@ func watch() error {
 	// prepare esbuild build options
 	buildEsbuildConfig(false)
 
-	// start esbuild server
-	esbuildContext, err := startEsbuildServe()
+	if err := updateEnvTypes(); err != nil {
+		return err
+	}
+
+	// start esbuild context
+	esbuildContext, err := startEsbuildContext()
 	if err != nil {
 		return err
 	}
 
-	// wait a bit, cause stuff needs to settle
-	time.Sleep(250 * time.Millisecond)
+	// first build, so server has something to serve
+	if result := rebuildOutput(esbuildContext); len(result.Errors) > 0 {
+		lib.PrintError("Build failed, see browser for errors")
+	}
 
 	// schedule esbuild context cleanup
 	defer func() {
@@ -119,13 +128,16 @@ func watch() error {
 							esbuildContext = nil
 						}
 						buildEsbuildConfig(false)
-						esbuildContext, err = startEsbuildServe()
+						if err := updateEnvTypes(); err != nil {
+							lib.PrintError(err)
+						}
+						esbuildContext, err = startEsbuildContext()
 						if err != nil {
 							lib.PrintError(err)
 							os.Exit(1)
 						}
 						lib.PrintReload("Config change detected, reloading esbuild...")
-						time.Sleep(250 * time.Millisecond)
+						rebuildOutput(esbuildContext)
 						broker.Notifier <- []byte("reload")
 					}
 					continue
@@ -170,9 +182,41 @@ func watch() error {
 				}
 				lib.PrintError(err)
 			case <-timer.C:
-				lib.PrintReload("Change detected, reloading...")
-				changedNames = nil
-				broker.Notifier <- []byte("reload")
+				// build now to show errors over running app instead of reloading into error page
+				if esbuildContext != nil {
+					result := rebuildOutput(esbuildContext)
+					data, _ := json.Marshal(makeBuildReport(result))
+					broker.Events <- lib.Event{Name: "build", Data: data}
+					if len(result.Errors) > 0 {
+						lib.PrintError("Build failed, see browser for errors")
+						changedNames = nil
+						continue
+					}
+				}
+
+				if isCSSOnlyChange(changedNames) {
+					// swap stylesheets in place, keeps app state
+					lib.PrintReload("CSS change detected, replacing styles...")
+					data, _ := json.Marshal(changedNames)
+					changedNames = nil
+					broker.Events <- lib.Event{Name: "css", Data: data}
+				} else if isHMRChange(changedNames) {
+					// push changed modules, client falls back to reload if it cannot apply them
+					updates, err := buildHMRUpdates(absWalkPath, changedNames)
+					changedNames = nil
+					if err != nil {
+						lib.PrintReload("Change detected, reloading...")
+						broker.Notifier <- []byte("reload")
+						continue
+					}
+					lib.PrintReload("Change detected, hot updating modules...")
+					data, _ := json.Marshal(updates)
+					broker.Events <- lib.Event{Name: "hmr", Data: data}
+				} else {
+					lib.PrintReload("Change detected, reloading...")
+					changedNames = nil
+					broker.Notifier <- []byte("reload")
+				}
 			}
 		}
 	}()
@@ -184,43 +228,21 @@ func watch() error {
 			protocol = "http://"
 		}
 
-		indexExists := lib.FileExists(filepath.Join(config.StaticDir, "index.html"))
-		baseIndexExists := false
-		baseIndexFile := filepath.Join(baseDir, "index.html")
-		if !indexExists {
-			baseIndexExists = lib.FileExists(baseIndexFile)
-		}
-
-		fileServer := lib.PipedFileServerWithMiddleware(config.StaticDir, pipeRequestToEsbuild, func(next http.HandlerFunc) http.HandlerFunc {
+		fileServer := lib.PipedFileServerWithMiddleware(config.StaticDir, serveBuildOutput, func(next http.HandlerFunc) http.HandlerFunc {
 			return func(writer http.ResponseWriter, request *http.Request) {
-				//pipe index directly to esbuild to skip loading of index by staticServer
-				if request.URL.Path == "/index.html" || filepath.Ext(request.URL.Path) == "" {
-					if indexExists {
-						pipeRequestToEsbuild(writer, request)
-					} else if baseIndexExists {
-						readBody, err := os.ReadFile(baseIndexFile)
-
-						if err != nil {
-							error404(writer, true)
-							return
-						}
-
-						index, _ := lib.InjectVarsIntoIndex(readBody, config.EntryFileName, config.AssetsDir, config.PublicURL)
-
-						writer.Header().Set("Content-Type", "text/html; charset=utf-8")
-						writer.Header().Set("Content-Length", fmt.Sprintf("%d", len(index)))
-						writer.WriteHeader(200)
-						_, _ = writer.Write(index)
-					} else {
-						error404(writer, true)
-					}
+				// serve page html directly to skip loading of index by staticServer
+				if page, ok := findRequestPage(request.URL.Path); ok {
+					serveIndex(writer, page)
+					return
+				}
+				if serveInterpolatedFile(writer, request) {
 					return
 				}
 
 				next(writer, request)
 			}
 		})
-		http.HandleFunc("/", fileServer)
+		http.HandleFunc("/", lib.BuildChain(fileServer, proxyMiddleware))
 
 		broker = lib.NewStreamServer()
 		http.Handle("/esbuild", broker)
@@ -235,6 +257,13 @@ func watch() error {
 		config.Port = socket.Addr().(*net.TCPAddr).Port
 
 		lib.PrintInfof("Listening on: %s%s:%d\n", protocol, config.Host, config.Port)
+		for _, rule := range config.Proxy {
+			if rule.Fallback {
+				lib.PrintInfof("Proxy: unknown requests -> %s\n", rule.Target)
+			} else {
+				lib.PrintInfof("Proxy: %s -> %s\n", rule.Path, rule.Target)
+			}
+		}
 
 		if isSecured {
 			err = http.ServeTLS(socket, nil, certFile, keyFile)
@@ -270,6 +299,19 @@ var ignoreDirs = map[string]bool{
 	".config":       true,
 }
 
+// isCSSOnlyChange checks if all changed files are plain stylesheets, css modules change js too so they need reload
+func isCSSOnlyChange(names []string) bool {
+	if len(names) == 0 {
+		return false
+	}
+	for _, name := range names {
+		if filepath.Ext(name) != ".css" || strings.HasSuffix(name, ".module.css") {
+			return false
+		}
+	}
+	return true
+}
+
 // watchDir gets run as a walk func, searching for directories to add watchers to
 func watchDir(watcher *fsnotify.Watcher) fs.WalkDirFunc {
 	return func(path string, fi os.DirEntry, err error) error {
@@ -300,87 +342,130 @@ func watchDir(watcher *fsnotify.Watcher) fs.WalkDirFunc {
 //	}
 //}
 
-func pipeRequestToEsbuild(w http.ResponseWriter, r *http.Request) {
-	var uri string
-	// if not file request then go to index
-	if filepath.Ext(r.URL.Path) == "" {
-		uri = "/index.html"
-	} else {
-		uri = r.URL.RequestURI()
+// serveBuildOutput serves files from last build kept in memory
+func serveBuildOutput(w http.ResponseWriter, r *http.Request) {
+	contents, modTime, ok := output.file(path.Clean(r.URL.Path))
+	if !ok {
+		error404(w, true)
+		return
 	}
 
-	var isIndex bool
-	if uri == "/index.html" {
-		isIndex = true
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, r.URL.Path, modTime, bytes.NewReader(contents))
+}
+
+// serveInterpolatedFile serves static file matching 'interpolate' globs with replaced vars, returns false if request is not for such file
+func serveInterpolatedFile(w http.ResponseWriter, r *http.Request) bool {
+	urlPath := path.Clean(r.URL.Path)
+	if !lib.MatchesGlobs(urlPath, config.Interpolate) {
+		return false
 	}
 
-	// normalize path prefix
-	uri = strings.TrimPrefix(uri, "/")
-
-	// get the file from esbuild
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second) // cancel after minute
-	defer cancel()
-	req, _ := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("http://%s:%d/%s", config.Host, proxyPort, uri), nil)
-	req.Header.Set("Host", r.Header.Get("Host"))
-	setXForwardedFrom(req, r)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	filePath := filepath.Join(config.StaticDir, filepath.FromSlash(urlPath))
+	info, err := os.Stat(filePath)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	contents, err := os.ReadFile(filePath)
 	if err != nil {
-		lib.PrintError(err)
-		if !errors.Is(err, syscall.EPIPE) {
-			error404(w, true)
-		}
-		return
+		return false
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != 200 && resp.StatusCode != 204 && resp.StatusCode != 206 {
-		// esbuild errors
-		if isIndex && resp.StatusCode == http.StatusServiceUnavailable && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			_, _ = fmt.Fprintf(w, "<!doctype html><head><meta charset=utf-8><title>error</title><script>%s</script></head><body><pre>", reloadJS)
-			_, err := io.Copy(w, resp.Body)
-			if err != nil {
-				_, _ = w.Write([]byte("Error: cannot build app"))
-			}
-			_, _ = w.Write([]byte("</pre></body>"))
-			return
+	contents, _ = lib.InterpolateVars(contents, interpolationVars)
+
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, filePath, info.ModTime(), bytes.NewReader(contents))
+	return true
+}
+
+// findRequestPage returns page for html request, page output file or any path without extension under page route
+func findRequestPage(urlPath string) (lib.Page, bool) {
+	pages := config.EntryPages()
+	for _, page := range pages {
+		if urlPath == path.Clean("/"+filepath.ToSlash(page.Output)) {
+			return page, true
 		}
-		error404(w, true)
-		return
 	}
+	if path.Ext(urlPath) != "" {
+		return lib.Page{}, false
+	}
+	return lib.FindPage(pages, urlPath)
+}
 
-	// copy esbuild headers
-	copyHeaders(w.Header(), resp.Header)
+// serveIndex serves page html with injected js/css, or build errors if last build failed
+func serveIndex(w http.ResponseWriter, page lib.Page) {
+	var index []byte
+	status := http.StatusOK
 
-	// replace custom vars in index.html and inject js/css scripts from esbuild
-	if isIndex {
-		// read esbuild response
-		readBody, err := io.ReadAll(resp.Body)
+	if errs := output.buildErrors(); len(errs) > 0 {
+		status = http.StatusServiceUnavailable
+		index = errorPage(api.FormatMessages(errs, api.FormatMessagesOptions{Kind: api.ErrorMessage}))
+	} else {
+		readBody, err := readPageTemplate(page)
 		if err != nil {
-			lib.PrintError(err)
-			if !errors.Is(err, syscall.EPIPE) {
-				error404(w, false)
-			}
+			error404(w, true)
 			return
 		}
 
-		index, _ := lib.InjectVarsIntoIndex(readBody, config.EntryFileName, config.AssetsDir, config.PublicURL)
-		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(index)))
-		w.WriteHeader(resp.StatusCode)
-		_, _ = w.Write(index)
-	} else {
-		w.WriteHeader(resp.StatusCode)
-		// copy esbuild response
-		_, err = io.Copy(w, resp.Body)
+		if config.HTMLTemplate {
+			readBody, err = lib.RenderIndexTemplate(page.Output, readBody, indexTemplateData(output.metadata(), config.StaticDir))
+		}
+
 		if err != nil {
-			lib.PrintError(err)
-			if !errors.Is(err, syscall.EPIPE) {
-				error404(w, false)
+			status = http.StatusInternalServerError
+			index = errorPage([]string{err.Error()})
+		} else {
+			readBody, _ = lib.InterpolateVars(readBody, interpolationVars)
+
+			if jsPath, cssPath, ok := findEntryOutputs(output.metadata(), filepath.Join(config.SourceDir, page.Entry), config.StaticDir); ok {
+				index, _ = lib.InjectAssetsIntoIndex(readBody, jsPath, cssPath, config.PublicURL)
+			} else {
+				index, _ = lib.InjectVarsIntoIndex(readBody, page.Entry, config.AssetsDir, config.PublicURL)
 			}
-			return
 		}
 	}
+
+	if nonce := setDevCSP(w); nonce != "" {
+		index = lib.InjectNonceIntoIndex(index, nonce)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(index)))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	_, _ = w.Write(index)
+}
+
+// errorPage is html page showing messages, it reloads when next build is done
+func errorPage(messages []string) []byte {
+	page := fmt.Appendf(nil, "<!doctype html><head><meta charset=utf-8><title>error</title><script>window.nrbBroken=1;%s</script></head><body><pre>", reloadJS)
+	for _, msg := range messages {
+		page = append(page, html.EscapeString(msg)...)
+	}
+	return append(page, "</pre></body>"...)
+}
+
+// setDevCSP sends csp header with fresh nonce for inline scripts/styles, returns the nonce or empty string if csp is off
+func setDevCSP(w http.ResponseWriter) string {
+	if config.CSP == "" {
+		return ""
+	}
+
+	nonceBytes := make([]byte, 16)
+	_, _ = rand.Read(nonceBytes)
+	nonce := base64.StdEncoding.EncodeToString(nonceBytes)
+
+	policy := lib.ParseCSP(config.CSP).
+		AddSources("script-src", "'nonce-"+nonce+"'").
+		AddSources("style-src", "'nonce-"+nonce+"'").
+		// dev client listens for changes on /esbuild
+		AddSources("connect-src", "'self'")
+	if hmrEnabled {
+		// hot updates get imported from blob urls
+		policy = policy.AddSources("script-src", "blob:")
+	}
+
+	w.Header().Set("Content-Security-Policy", policy.String())
+	return nonce
 }
 
 func setXForwardedFrom(req *http.Request, src *http.Request) {
@@ -402,14 +487,6 @@ func setXForwardedFrom(req *http.Request, src *http.Request) {
 	}
 }
 
-func copyHeaders(dst, src http.Header) {
-	for k, vv := range src {
-		for _, v := range vv {
-			dst.Set(k, v)
-		}
-	}
-}
-
 func error404(res http.ResponseWriter, writeHeader bool) {
 	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	res.Header().Del("Content-Length")
@@ -420,7 +497,7 @@ func error404(res http.ResponseWriter, writeHeader bool) {
 	_, _ = res.Write([]byte("404 - Not Found"))
 }
 
-func startEsbuildServe() (api.BuildContext, error) {
+func startEsbuildContext() (api.BuildContext, error) {
 	// inject hot reload watcher to js
 	if buildOptions.Banner == nil {
 		buildOptions.Banner = map[string]string{"js": reloadJS}
@@ -435,31 +512,82 @@ func startEsbuildServe() (api.BuildContext, error) {
 	// set outdir
 	buildOptions.Outdir = filepath.Join(config.StaticDir, config.AssetsDir)
 
-	// dont write files on watch
+	// hot module replacement
+	setupHMR()
+
+	// keep files in memory on watch
 	buildOptions.Write = false
 
+	// metafile tells real entry output names for index
+	buildOptions.Metafile = true
+
 	// get esbuild context
 	ctx, ctxerr := api.Context(buildOptions)
 	if ctxerr != nil {
 		return nil, ctxerr
 	}
 
-	// start esbuild server
-	server, err := ctx.Serve(api.ServeOptions{
-		Servedir: config.StaticDir,
-		Port:     int(proxyPort),
-		Host:     config.Host,
-	})
+	return ctx, nil
+}
 
-	if err != nil {
-		return nil, err
-	}
+// memoryOutput keeps files from last watch build, urls are relative to static dir
+type memoryOutput struct {
+	mu       sync.RWMutex
+	files    map[string][]byte
+	metafile Metadata
+	errors   []api.Message
+	time     time.Time
+}
+
+var output memoryOutput
 
-	// sync values used by esbuild to real used ones
-	proxyPort = server.Port
-	// nope, dont need to know/change - host = server.Hosts[0]
+func (o *memoryOutput) file(urlPath string) ([]byte, time.Time, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	contents, ok := o.files[urlPath]
+	return contents, o.time, ok
+}
 
-	//lib.PrintOk("Esbuild start done")
+func (o *memoryOutput) metadata() Metadata {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.metafile
+}
 
-	return ctx, nil
+func (o *memoryOutput) buildErrors() []api.Message {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.errors
+}
+
+// update stores build result, failed build keeps previous files so running app can still load its chunks
+func (o *memoryOutput) update(result api.BuildResult, staticDir string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.errors = result.Errors
+	if len(result.Errors) > 0 {
+		return
+	}
+
+	absStaticDir, _ := filepath.Abs(staticDir)
+	files := make(map[string][]byte, len(result.OutputFiles))
+	for _, file := range result.OutputFiles {
+		rel, err := filepath.Rel(absStaticDir, file.Path)
+		if err != nil {
+			continue
+		}
+		files["/"+filepath.ToSlash(rel)] = file.Contents
+	}
+	o.files = files
+	o.metafile = Metadata{}
+	_ = json.Unmarshal([]byte(result.Metafile), &o.metafile)
+	o.time = time.Now()
+}
+
+// rebuildOutput builds the app and keeps the result in memory for the server
+func rebuildOutput(ctx api.BuildContext) api.BuildResult {
+	result := ctx.Rebuild()
+	output.update(result, config.StaticDir)
+	return result
 }
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/natrim/nrb/lib"
+)
+
+func TestIsCSSOnlyChange(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  bool
+	}{
+		{name: "no names", names: nil, want: false},
+		{name: "plain css", names: []string{"index.css", "components/button.css"}, want: true},
+		{name: "css module", names: []string{"button.module.css"}, want: false},
+		{name: "mixed", names: []string{"index.css", "App.tsx"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCSSOnlyChange(tt.names); got != tt.want {
+				t.Fatalf("isCSSOnlyChange(%v) = %v, want %v", tt.names, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHMRChangeNeedsEnabledHMRAndScripts(t *testing.T) {
+	t.Cleanup(func() {
+		hmrEnabled = false
+	})
+
+	hmrEnabled = false
+	if isHMRChange([]string{"App.tsx"}) {
+		t.Fatal("did not expect hmr change when hmr is disabled")
+	}
+
+	hmrEnabled = true
+	if !isHMRChange([]string{"App.tsx", "components/Button.jsx"}) {
+		t.Fatal("expected script changes to be hot updated")
+	}
+	if isHMRChange([]string{"App.tsx", "index.css"}) {
+		t.Fatal("did not expect mixed changes to be hot updated")
+	}
+}
+
+func TestSetupHMRDefinesFastRefreshOnlyWhenHMRIsUsed(t *testing.T) {
+	t.Cleanup(func() {
+		hmrEnabled = false
+		resetRuntimeBridgeState()
+	})
+
+	resetRuntimeBridgeState()
+	baseDir = t.TempDir()
+	config.HMR = true
+	buildOptions.Define = map[string]string{"process.env.FAST_REFRESH": "false"}
+
+	// hmr is on in config, but react-refresh is not installed
+	setupHMR()
+	if got := buildOptions.Define["process.env.FAST_REFRESH"]; got != "false" {
+		t.Fatalf("process.env.FAST_REFRESH = %s without react-refresh, want false", got)
+	}
+
+	refreshDir := filepath.Join(baseDir, "node_modules", "react-refresh")
+	if err := os.MkdirAll(refreshDir, 0755); err != nil {
+		t.Fatalf("failed to create %s: %v", refreshDir, err)
+	}
+	writeFile(t, filepath.Join(refreshDir, "runtime.js"), "")
+	setupHMR()
+	if got := buildOptions.Define["process.env.FAST_REFRESH"]; got != "true" {
+		t.Fatalf("process.env.FAST_REFRESH = %s with react-refresh, want true", got)
+	}
+}
+
+func TestBuildHMRUpdatesReusesLoadedModules(t *testing.T) {
+	t.Cleanup(func() {
+		resetRuntimeBridgeState()
+	})
+
+	resetRuntimeBridgeState()
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "src")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	writeFile(t, filepath.Join(sourceDir, "util.ts"), "export const helper = () => \"!\";\n")
+	writeFile(t, filepath.Join(sourceDir, "App.tsx"), "import { helper } from \"./util\";\nimport \"./App.css\";\nexport default function App() { return helper(); }\n")
+	writeFile(t, filepath.Join(sourceDir, "App.css"), ".app{}\n")
+
+	config.SourceDir = sourceDir
+	buildOptions = api.BuildOptions{
+		Bundle: true,
+		Format: api.FormatESModule,
+		Outdir: filepath.Join(tempDir, "out"),
+	}
+
+	updates, err := buildHMRUpdates(sourceDir, []string{"App.tsx"})
+	if err != nil {
+		t.Fatalf("buildHMRUpdates returned error: %v", err)
+	}
+	if len(updates) != 1 {
+		t.Fatalf("expected 1 update, got %d", len(updates))
+	}
+
+	code := updates[0].Code
+	if !strings.Contains(code, "window.__nrbHmr.module(") || !strings.Contains(code, "util.ts") {
+		t.Fatalf("expected util import to come from loaded modules, got:\n%s", code)
+	}
+	if strings.Contains(code, "\"!\"") {
+		t.Fatalf("did not expect util module to be bundled again, got:\n%s", code)
+	}
+	if !strings.Contains(code, "window.__nrbHmr.accept(") {
+		t.Fatalf("expected update to accept itself, got:\n%s", code)
+	}
+}
+
+func writeFile(t *testing.T, path string, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestMakeBuildReportIncludesLocationAndCodeFrame(t *testing.T) {
+	report := makeBuildReport(api.BuildResult{
+		Errors: []api.Message{{
+			Text: "Expected \";\" but found \"}\"",
+			Location: &api.Location{
+				File:     "src/App.tsx",
+				Line:     12,
+				Column:   5,
+				Length:   1,
+				LineText: "\tfoo bar",
+			},
+		}},
+		Warnings: []api.Message{{Text: "unused import"}},
+	})
+
+	if len(report.Errors) != 1 || len(report.Warnings) != 1 {
+		t.Fatalf("expected 1 error and 1 warning, got %#v", report)
+	}
+
+	msg := report.Errors[0]
+	if msg.File != "src/App.tsx" || msg.Line != 12 || msg.Column != 5 {
+		t.Fatalf("unexpected error location: %#v", msg)
+	}
+	if want := "12 | \tfoo bar\n   | \t    ^"; msg.Frame != want {
+		t.Fatalf("Frame = %q, want %q", msg.Frame, want)
+	}
+	if report.Warnings[0].Frame != "" {
+		t.Fatalf("did not expect frame without location, got %q", report.Warnings[0].Frame)
+	}
+}
+
+func TestServeBuildOutputServesLastSuccessfulBuild(t *testing.T) {
+	t.Cleanup(func() {
+		output = memoryOutput{}
+	})
+
+	staticDir := t.TempDir()
+	absStaticDir, _ := filepath.Abs(staticDir)
+	output.update(api.BuildResult{OutputFiles: []api.OutputFile{
+		{Path: filepath.Join(absStaticDir, "assets", "index.js"), Contents: []byte("console.log(1)")},
+	}}, staticDir)
+
+	// failed build keeps files of the previous one
+	output.update(api.BuildResult{Errors: []api.Message{{Text: "boom"}}}, staticDir)
+	if errs := output.buildErrors(); len(errs) != 1 {
+		t.Fatalf("buildErrors() = %v, want 1 error", errs)
+	}
+
+	// watch serves build output after static dir misses
+	rec := httptest.NewRecorder()
+	lib.PipedFileServer(staticDir, serveBuildOutput)(rec, httptest.NewRequest(http.MethodGet, "/assets/../assets/index.js", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "console.log(1)" {
+		t.Fatalf("serveBuildOutput() = %d %q, want 200 %q", rec.Code, rec.Body.String(), "console.log(1)")
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/javascript") {
+		t.Fatalf("serveBuildOutput() Content-Type = %q, want text/javascript", got)
+	}
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "" {
+		t.Fatalf("serveBuildOutput() X-Content-Type-Options = %q, want none", got)
+	}
+
+	rec = httptest.NewRecorder()
+	serveBuildOutput(rec, httptest.NewRequest(http.MethodGet, "/assets/missing.js", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("serveBuildOutput() status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestServeInterpolatedFileReplacesVarsInMatchingFiles(t *testing.T) {
+	t.Cleanup(func() {
+		resetRuntimeBridgeState()
+	})
+
+	resetRuntimeBridgeState()
+	config.StaticDir = t.TempDir()
+	config.Interpolate = lib.ArrayFlags{"manifest.json"}
+	interpolationVars = lib.MapFlags{"PUBLIC_URL": "/app"}
+	writeFile(t, filepath.Join(config.StaticDir, "manifest.json"), `{"start_url":"%PUBLIC_URL%/"}`)
+	writeFile(t, filepath.Join(config.StaticDir, "robots.txt"), "%PUBLIC_URL%")
+
+	rec := httptest.NewRecorder()
+	if !serveInterpolatedFile(rec, httptest.NewRequest(http.MethodGet, "/manifest.json", nil)) {
+		t.Fatal("expected manifest.json to be interpolated")
+	}
+	if got, want := rec.Body.String(), `{"start_url":"/app/"}`; got != want {
+		t.Fatalf("serveInterpolatedFile() body = %q, want %q", got, want)
+	}
+
+	if serveInterpolatedFile(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/robots.txt", nil)) {
+		t.Fatal("did not expect robots.txt to be interpolated")
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/natrim/nrb/lib"
+)
+
+// importStep is one file in import chain, Kind is how previous file imports it, empty for entry
+type importStep struct {
+	Path string
+	Kind string
+}
+
+type importChain []importStep
+
+// isDynamic checks if chain goes through dynamic import()
+func (c importChain) isDynamic() bool {
+	return slices.ContainsFunc(c, func(step importStep) bool {
+		return step.Kind == "dynamic-import"
+	})
+}
+
+// moduleChunk is output with bytes of module in it and reason why it is loaded
+type moduleChunk struct {
+	Output string
+	Bytes  int64
+	Reason string
+	// order puts startup chunks first
+	order int
+}
+
+// whyCommand handles 'why' command, explains why module is in the bundle
+func whyCommand(args []string) error {
+	flags := flag.NewFlagSet("why", flag.ContinueOnError)
+	buildFlag := false
+	limitFlag := 10
+	flags.BoolVar(&buildFlag, "build", buildFlag, "build the app even if 'build-meta.json' exists in output dir")
+	flags.IntVar(&limitFlag, "limit", limitFlag, "max import chains to show for each entry, 0 for all")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("use why with one package or file, ie. 'nrb why lodash'")
+	}
+	module := flags.Arg(0)
+
+	_, metafile, err := loadMetafile(buildFlag)
+	if err != nil {
+		return err
+	}
+
+	inBundle := false
+	for input := range metafile.Inputs {
+		if matchesModule(input, module) {
+			inBundle = true
+			break
+		}
+	}
+	if !inBundle {
+		return fmt.Errorf("'%s' is not in the bundle", module)
+	}
+
+	for _, page := range config.EntryPages() {
+		entry := filepath.Join(config.SourceDir, page.Entry)
+		entryInput := metafileInput(entry)
+		lib.PrintInfof("%s from %s:\n", lib.Yellow(module), entryInput)
+
+		if entryOutput, ok := findEntryOutput(metafile, entry); ok {
+			for _, chunk := range moduleChunks(metafile, entryOutput, module) {
+				lib.PrintItemf("in %s %s, %s\n", outputPath(chunk.Output, config.OutputDir), lib.Blue("("+lib.FormatSize(chunk.Bytes)+")"), chunk.Reason)
+			}
+		}
+
+		chains := importChains(metafile, entryInput, module)
+		if len(chains) == 0 {
+			lib.PrintItem("not imported from this entry")
+			continue
+		}
+		for i, chain := range chains {
+			if limitFlag > 0 && i == limitFlag {
+				lib.PrintItemf("and %d more, use -limit=0 to show all\n", len(chains)-i)
+				break
+			}
+			printImportChain(chain)
+		}
+	}
+	return nil
+}
+
+func printImportChain(chain importChain) {
+	kind := "static"
+	if chain.isDynamic() {
+		kind = "dynamic"
+	}
+	lib.PrintItemf("%s import chain:\n", kind)
+	for i, step := range chain {
+		if i == 0 {
+			lib.Printf("    %s\n", step.Path)
+			continue
+		}
+		lib.Printf("    %s└ %s %s\n", strings.Repeat("  ", i-1), step.Path, lib.Blue("("+step.Kind+")"))
+	}
+}
+
+// importChains walks metafile input imports from entry, returns shortest chain to every file importing module, shortest first
+func importChains(metafile Metadata, entry, module string) []importChain {
+	if matchesModule(entry, module) {
+		return []importChain{{{Path: entry}}}
+	}
+
+	// previous step of every reached file
+	from := map[string]importStep{entry: {}}
+	chainTo := func(path string) importChain {
+		var chain importChain
+		for path != entry {
+			step := from[path]
+			chain = append(chain, importStep{Path: path, Kind: step.Kind})
+			path = step.Path
+		}
+		chain = append(chain, importStep{Path: entry})
+		slices.Reverse(chain)
+		return chain
+	}
+
+	var chains []importChain
+	queue := []string{entry}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		found := make(map[string]bool)
+		for _, imp := range metafile.Inputs[current].Imports {
+			if imp.External {
+				continue
+			}
+			if matchesModule(imp.Path, module) {
+				if !found[imp.Path] {
+					found[imp.Path] = true
+					chains = append(chains, append(chainTo(current), importStep{Path: imp.Path, Kind: imp.Kind}))
+				}
+				// files inside module import each other, that does not explain anything
+				continue
+			}
+			if _, ok := from[imp.Path]; !ok {
+				from[imp.Path] = importStep{Path: current, Kind: imp.Kind}
+				queue = append(queue, imp.Path)
+			}
+		}
+	}
+	return chains
+}
+
+// moduleChunks finds outputs with module files, reason says how entry loads the output
+func moduleChunks(metafile Metadata, entryOutput, module string) []moduleChunk {
+	staticChunks, dynamicChunks := entryChunks(metafile, entryOutput)
+
+	var chunks []moduleChunk
+	for _, out := range slices.Sorted(maps.Keys(metafile.Outputs)) {
+		var bytes int64
+		found := false
+		for input, i := range metafile.Outputs[out].Inputs {
+			if matchesModule(input, module) {
+				bytes += int64(i.BytesInOutput)
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+
+		chunk := moduleChunk{Output: out, Bytes: bytes}
+		switch {
+		case out == entryOutput:
+			chunk.Reason = "entry chunk, loaded at startup"
+		case slices.Contains(staticChunks, out):
+			chunk.Reason, chunk.order = "loaded at startup by static import of entry", 1
+		case slices.Contains(dynamicChunks, out):
+			chunk.Reason, chunk.order = "lazy chunk, loaded by dynamic import()", 2
+		default:
+			chunk.Reason, chunk.order = "not loaded by this entry", 3
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	slices.SortStableFunc(chunks, func(a, b moduleChunk) int {
+		return a.order - b.order
+	})
+	return chunks
+}
+
+// matchesModule checks if metafile input is file of package or is the file, file can be relative to working dir or end of path
+func matchesModule(input, module string) bool {
+	if name, _, ok := lib.PackageOfPath(input); ok && name == module {
+		return true
+	}
+	file := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(module)), "./")
+	return input == file || strings.HasSuffix(input, "/"+file)
+}
+
+// metafileInput converts file path to metafile input path, which is relative to working dir
+func metafileInput(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(wd, absPath)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
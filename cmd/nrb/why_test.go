@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestImportChainsFindsShortestChainToEveryImporter(t *testing.T) {
+	var metafile Metadata
+	err := json.Unmarshal([]byte(`{
+		"inputs": {
+			"src/index.tsx": {"imports": [
+				{"path": "src/App.tsx", "kind": "import-statement"},
+				{"path": "src/Settings.tsx", "kind": "dynamic-import"},
+				{"path": "react", "kind": "import-statement", "external": true}
+			]},
+			"src/App.tsx": {"imports": [
+				{"path": "src/utils.ts", "kind": "import-statement"},
+				{"path": "node_modules/lodash/get.js", "kind": "import-statement"}
+			]},
+			"src/Settings.tsx": {"imports": [
+				{"path": "src/utils.ts", "kind": "import-statement"},
+				{"path": "node_modules/lodash/set.js", "kind": "require-call"}
+			]},
+			"src/utils.ts": {"imports": [
+				{"path": "node_modules/lodash/get.js", "kind": "import-statement"}
+			]},
+			"node_modules/lodash/get.js": {"imports": [{"path": "node_modules/lodash/_base.js", "kind": "require-call"}]},
+			"node_modules/lodash/set.js": {"imports": [{"path": "node_modules/lodash/_base.js", "kind": "require-call"}]},
+			"node_modules/lodash/_base.js": {}
+		},
+		"outputs": {
+			"build/index.js": {"entryPoint": "src/index.tsx", "imports": [{"path": "build/chunks/Settings.js", "kind": "dynamic-import"}], "inputs": {
+				"src/index.tsx": {"bytesInOutput": 10},
+				"node_modules/lodash/get.js": {"bytesInOutput": 100},
+				"node_modules/lodash/_base.js": {"bytesInOutput": 50}
+			}},
+			"build/chunks/Settings.js": {"inputs": {"node_modules/lodash/set.js": {"bytesInOutput": 70}}}
+		}
+	}`), &metafile)
+	if err != nil {
+		t.Fatalf("failed to parse metafile: %v", err)
+	}
+
+	want := []importChain{
+		{{Path: "src/index.tsx"}, {Path: "src/App.tsx", Kind: "import-statement"}, {Path: "node_modules/lodash/get.js", Kind: "import-statement"}},
+		{{Path: "src/index.tsx"}, {Path: "src/Settings.tsx", Kind: "dynamic-import"}, {Path: "node_modules/lodash/set.js", Kind: "require-call"}},
+		{{Path: "src/index.tsx"}, {Path: "src/App.tsx", Kind: "import-statement"}, {Path: "src/utils.ts", Kind: "import-statement"}, {Path: "node_modules/lodash/get.js", Kind: "import-statement"}},
+	}
+	chains := importChains(metafile, "src/index.tsx", "lodash")
+	if !reflect.DeepEqual(chains, want) {
+		t.Fatalf("importChains() = %+v, want %+v", chains, want)
+	}
+	if chains[0].isDynamic() || !chains[1].isDynamic() {
+		t.Fatal("isDynamic() want only chain through Settings.tsx dynamic")
+	}
+
+	if chains := importChains(metafile, "src/index.tsx", "src/utils.ts"); len(chains) != 2 || chains[0][1].Path != "src/App.tsx" || chains[1][1].Path != "src/Settings.tsx" {
+		t.Fatalf("importChains() of file = %+v, want chains through App.tsx and Settings.tsx", chains)
+	}
+
+	chunks := moduleChunks(metafile, "build/index.js", "lodash")
+	wantChunks := []moduleChunk{
+		{Output: "build/index.js", Bytes: 150, Reason: "entry chunk, loaded at startup"},
+		{Output: "build/chunks/Settings.js", Bytes: 70, Reason: "lazy chunk, loaded by dynamic import()", order: 2},
+	}
+	if !reflect.DeepEqual(chunks, wantChunks) {
+		t.Fatalf("moduleChunks() = %+v, want %+v", chunks, wantChunks)
+	}
+}
@@ -3,9 +3,10 @@ module github.com/natrim/nrb
 go 1.26
 
 require (
+	github.com/andybalholm/brotli v1.2.6
 	github.com/evanw/esbuild v0.28.0
 	github.com/fsnotify/fsnotify v1.9.0
-	github.com/joho/godotenv v1.5.1
+	golang.org/x/net v0.57.0
 )
 
-require golang.org/x/sys v0.13.0 // indirect
+require golang.org/x/sys v0.47.0 // indirect
@@ -0,0 +1,242 @@
+package lib
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Budget is max size in bytes of raw and gzipped file, 0 means no limit
+type Budget struct {
+	Raw  int64
+	Gzip int64
+}
+
+// AssetBudget is budget of every output file matching glob
+type AssetBudget struct {
+	Glob   string
+	Budget Budget
+}
+
+// Budgets are bundle size limits checked on build
+type Budgets struct {
+	// Entry is budget of every entry chunk
+	Entry Budget
+	// Chunk is budget of every js chunk
+	Chunk Budget
+	// JS and CSS are budgets of all js and css files together
+	JS     Budget
+	CSS    Budget
+	Assets []AssetBudget
+}
+
+// BundleFile is built file with its sizes, Path is relative to output dir
+type BundleFile struct {
+	Path   string `json:"path"`
+	Entry  bool   `json:"entry,omitempty"`
+	Raw    int64  `json:"raw"`
+	Gzip   int64  `json:"gzip"`
+	Brotli int64  `json:"brotli"`
+	// Inputs are metafile input paths bundled in the file, they tell apart chunks with same name
+	Inputs []string `json:"-"`
+}
+
+// BudgetViolation is size over budget limit
+type BudgetViolation struct {
+	Budget string
+	Path   string
+	// Kind is raw or gzip
+	Kind  string
+	Size  int64
+	Limit int64
+}
+
+// IsSet checks if some budget is set
+func (b Budgets) IsSet() bool {
+	return b.Entry != (Budget{}) || b.Chunk != (Budget{}) || b.JS != (Budget{}) || b.CSS != (Budget{}) || len(b.Assets) > 0
+}
+
+// Check returns violations of budgets by built files
+func (b Budgets) Check(files []BundleFile) []BudgetViolation {
+	var violations []BudgetViolation
+	var js, css BundleFile
+	js.Path = "all .js"
+	css.Path = "all .css"
+
+	for _, file := range files {
+		switch strings.ToLower(path.Ext(file.Path)) {
+		case ".js":
+			if file.Entry {
+				violations = b.Entry.check(violations, "entry", file)
+			}
+			violations = b.Chunk.check(violations, "chunk", file)
+			js.Raw += file.Raw
+			js.Gzip += file.Gzip
+		case ".css":
+			css.Raw += file.Raw
+			css.Gzip += file.Gzip
+		}
+
+		for _, asset := range b.Assets {
+			if MatchesGlobs(file.Path, []string{asset.Glob}) {
+				violations = asset.Budget.check(violations, asset.Glob, file)
+			}
+		}
+	}
+
+	violations = b.JS.check(violations, "js", js)
+	violations = b.CSS.check(violations, "css", css)
+	return violations
+}
+
+func (budget Budget) check(violations []BudgetViolation, name string, file BundleFile) []BudgetViolation {
+	if budget.Raw > 0 && file.Raw > budget.Raw {
+		violations = append(violations, BudgetViolation{Budget: name, Path: file.Path, Kind: "raw", Size: file.Raw, Limit: budget.Raw})
+	}
+	if budget.Gzip > 0 && file.Gzip > budget.Gzip {
+		violations = append(violations, BudgetViolation{Budget: name, Path: file.Path, Kind: "gzip", Size: file.Gzip, Limit: budget.Gzip})
+	}
+	return violations
+}
+
+// ParseSize parses size in bytes from number or string with b, kb or mb unit (kb is 1024 bytes), ie. "250kb"
+func ParseSize(value any) (int64, error) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 {
+			return 0, fmt.Errorf("size %v is negative", v)
+		}
+		return int64(v), nil
+	case string:
+		s := strings.ToLower(strings.TrimSpace(v))
+		multiplier := 1.0
+		if number, ok := strings.CutSuffix(s, "kb"); ok {
+			s, multiplier = number, 1024
+		} else if number, ok := strings.CutSuffix(s, "mb"); ok {
+			s, multiplier = number, 1024*1024
+		}
+		s = strings.TrimSpace(strings.TrimSuffix(s, "b"))
+		number, err := strconv.ParseFloat(s, 64)
+		if err != nil || number < 0 {
+			return 0, fmt.Errorf("wrong size '%s', use bytes or string like 250kb", v)
+		}
+		return int64(number * multiplier), nil
+	default:
+		return 0, fmt.Errorf("wrong size '%v', use bytes or string like 250kb", value)
+	}
+}
+
+// GzipSize is size of gzipped content with default compression, as most servers send it
+func GzipSize(content []byte) int64 {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	_, _ = w.Write(content)
+	_ = w.Close()
+	return int64(b.Len())
+}
+
+// FormatSize formats bytes for humans
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.2f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.2f kB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+func parseBudgets(options map[string]any, key string, target **Budgets) error {
+	value, ok := options[key]
+	if !ok {
+		return nil
+	}
+
+	rawMap, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("wrong '%s' key in 'package.json', use object", key)
+	}
+
+	budgets := &Budgets{}
+	for name, rawBudget := range rawMap {
+		if name == "assets" {
+			assets, ok := rawBudget.(map[string]any)
+			if !ok {
+				return fmt.Errorf("wrong '%s.assets' key in 'package.json', use object of glob and budget", key)
+			}
+			for glob, rawAsset := range assets {
+				if err := CheckGlobs([]string{glob}); err != nil {
+					return fmt.Errorf("wrong '%s.assets' key in 'package.json', %w", key, err)
+				}
+				budget, err := parseBudget(rawAsset, key+".assets."+glob)
+				if err != nil {
+					return err
+				}
+				budgets.Assets = append(budgets.Assets, AssetBudget{Glob: glob, Budget: budget})
+			}
+			continue
+		}
+
+		var dst *Budget
+		switch name {
+		case "entry":
+			dst = &budgets.Entry
+		case "chunk":
+			dst = &budgets.Chunk
+		case "js":
+			dst = &budgets.JS
+		case "css":
+			dst = &budgets.CSS
+		default:
+			return fmt.Errorf("wrong '%s.%s' key in 'package.json', use entry|chunk|js|css|assets", key, name)
+		}
+		budget, err := parseBudget(rawBudget, key+"."+name)
+		if err != nil {
+			return err
+		}
+		*dst = budget
+	}
+
+	// globs in stable order, so violations are too
+	slices.SortFunc(budgets.Assets, func(a, b AssetBudget) int {
+		return strings.Compare(a.Glob, b.Glob)
+	})
+
+	*target = budgets
+	return nil
+}
+
+// parseBudget parses size of raw file, or object with 'raw' and 'gzip' sizes
+func parseBudget(value any, key string) (Budget, error) {
+	var budget Budget
+	rawMap, ok := value.(map[string]any)
+	if !ok {
+		size, err := ParseSize(value)
+		if err != nil {
+			return budget, fmt.Errorf("wrong '%s' key in 'package.json', %w", key, err)
+		}
+		budget.Raw = size
+		return budget, nil
+	}
+
+	for kind, rawSize := range rawMap {
+		size, err := ParseSize(rawSize)
+		if err != nil {
+			return budget, fmt.Errorf("wrong '%s.%s' key in 'package.json', %w", key, kind, err)
+		}
+		switch kind {
+		case "raw":
+			budget.Raw = size
+		case "gzip":
+			budget.Gzip = size
+		default:
+			return budget, fmt.Errorf("wrong '%s.%s' key in 'package.json', use raw|gzip", key, kind)
+		}
+	}
+	return budget, nil
+}
@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBudgets(t *testing.T) {
+	patch, err := ParseJsonConfig(PackageJson{"nrb": map[string]any{"budgets": map[string]any{
+		"entry":  map[string]any{"raw": "200kb", "gzip": "60kb"},
+		"chunk":  float64(100000),
+		"js":     "1.5mb",
+		"css":    map[string]any{"gzip": "20kb"},
+		"assets": map[string]any{"media/*.png": "50kb", "*.svg": "5 kb"},
+	}}})
+	if err != nil {
+		t.Fatalf("ParseJsonConfig() returned error: %v", err)
+	}
+
+	want := &Budgets{
+		Entry: Budget{Raw: 200 * 1024, Gzip: 60 * 1024},
+		Chunk: Budget{Raw: 100000},
+		JS:    Budget{Raw: 1536 * 1024},
+		CSS:   Budget{Gzip: 20 * 1024},
+		Assets: []AssetBudget{
+			{Glob: "*.svg", Budget: Budget{Raw: 5 * 1024}},
+			{Glob: "media/*.png", Budget: Budget{Raw: 50 * 1024}},
+		},
+	}
+	if !reflect.DeepEqual(patch.Budgets, want) {
+		t.Fatalf("Budgets = %+v, want %+v", patch.Budgets, want)
+	}
+}
+
+func TestParseBudgetsRejectsInvalidBudgets(t *testing.T) {
+	tests := map[string]any{
+		"unknown budget": map[string]any{"total": "1mb"},
+		"unknown kind":   map[string]any{"entry": map[string]any{"brotli": "1mb"}},
+		"bad size":       map[string]any{"chunk": "big"},
+		"negative size":  map[string]any{"chunk": float64(-1)},
+		"bad assets":     map[string]any{"assets": "50kb"},
+		"bad glob":       map[string]any{"assets": map[string]any{"[": "50kb"}},
+		"not an object":  "200kb",
+	}
+
+	for name, budgets := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseJsonConfig(PackageJson{"nrb": map[string]any{"budgets": budgets}}); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestBudgetsCheckListsViolations(t *testing.T) {
+	budgets := Budgets{
+		Entry:  Budget{Gzip: 100},
+		Chunk:  Budget{Raw: 1000},
+		JS:     Budget{Raw: 2000},
+		CSS:    Budget{Raw: 500},
+		Assets: []AssetBudget{{Glob: "*.png", Budget: Budget{Raw: 300}}},
+	}
+	files := []BundleFile{
+		{Path: "assets/index.js", Entry: true, Raw: 900, Gzip: 150},
+		{Path: "assets/lazy.js", Raw: 1200, Gzip: 90},
+		{Path: "assets/index.css", Raw: 400, Gzip: 50},
+		{Path: "assets/logo.png", Raw: 301},
+	}
+
+	want := []BudgetViolation{
+		{Budget: "entry", Path: "assets/index.js", Kind: "gzip", Size: 150, Limit: 100},
+		{Budget: "chunk", Path: "assets/lazy.js", Kind: "raw", Size: 1200, Limit: 1000},
+		{Budget: "*.png", Path: "assets/logo.png", Kind: "raw", Size: 301, Limit: 300},
+		{Budget: "js", Path: "all .js", Kind: "raw", Size: 2100, Limit: 2000},
+	}
+	if got := budgets.Check(files); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Check() = %+v, want %+v", got, want)
+	}
+}
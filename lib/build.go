@@ -2,32 +2,130 @@ package lib
 
 import (
 	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
 	"path/filepath"
 	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
 // InjectVarsIntoIndex injects js/css import to index.html content, returns bool if injected into content
 func InjectVarsIntoIndex(indexFile []byte, entryFileName, assetsDir, publicUrl string) ([]byte, bool) {
 	indexFileName := strings.TrimSuffix(filepath.Base(entryFileName), filepath.Ext(entryFileName))
+	return InjectAssetsIntoIndex(indexFile, "/"+assetsDir+"/"+indexFileName+".js", "/"+assetsDir+"/"+indexFileName+".css", publicUrl)
+}
+
+// InjectAssetsIntoIndex injects entry script and stylesheet paths (relative to publicUrl) to index.html content, empty cssPath skips stylesheet, returns bool if injected into content
+//
+// head tags go to <!-- nrb:head --> marker or end of head, script goes to <!-- nrb:body --> marker or end of body
+func InjectAssetsIntoIndex(indexFile []byte, jsPath, cssPath, publicUrl string) ([]byte, bool) {
 	publicUrl = strings.TrimSuffix(publicUrl, "/")
 	changed := false
 
+	// replace %PUBLIC_URL%
+	if bytes.Contains(indexFile, []byte("%PUBLIC_URL%")) {
+		changed = true
+		indexFile = bytes.ReplaceAll(indexFile, []byte("%PUBLIC_URL%"), []byte(publicUrl))
+	}
+
+	doc := parseIndex(indexFile)
+
 	//inject main js/css if not already in index.html
-	if !bytes.Contains(indexFile, []byte("/"+assetsDir+"/"+indexFileName+".css")) {
+	if cssPath != "" && !doc.hasAsset(cssPath, publicUrl) {
 		changed = true
-		indexFile = bytes.Replace(indexFile, []byte("</head>"), []byte("<link rel=\"preload\" href=\""+publicUrl+"/"+assetsDir+"/"+indexFileName+".css\" as=\"style\">\n<link rel=\"stylesheet\" href=\""+publicUrl+"/"+assetsDir+"/"+indexFileName+".css\">\n</head>"), 1)
+		doc = doc.insert(doc.headIndex(),
+			newTag(atom.Link, "rel", "preload", "href", publicUrl+cssPath, "as", "style"), newLine(),
+			newTag(atom.Link, "rel", "stylesheet", "href", publicUrl+cssPath), newLine(),
+		)
 	}
-	if !bytes.Contains(indexFile, []byte("/"+assetsDir+"/"+indexFileName+".js")) {
+	if !doc.hasAsset(jsPath, publicUrl) {
 		changed = true
-		indexFile = bytes.Replace(indexFile, []byte("</body>"), []byte("<script type=\"module\" src=\""+publicUrl+"/"+assetsDir+"/"+indexFileName+".js\"></script>\n</body>"), 1)
-		indexFile = bytes.Replace(indexFile, []byte("</head>"), []byte("<link rel=\"modulepreload\" href=\""+publicUrl+"/"+assetsDir+"/"+indexFileName+".js\">\n</head>"), 1)
+		doc = doc.insert(doc.bodyIndex(), newTag(atom.Script, "type", "module", "src", publicUrl+jsPath), newEndTag(atom.Script), newLine())
+		doc = doc.insert(doc.headIndex(), newTag(atom.Link, "rel", "modulepreload", "href", publicUrl+jsPath), newLine())
 	}
 
-	// replace %PUBLIC_URL%
-	if bytes.Contains(indexFile, []byte("%PUBLIC_URL%")) {
+	if !changed {
+		return indexFile, false
+	}
+	return doc.Bytes(), true
+}
+
+// InjectLinksIntoIndex injects link tags with rel for paths (relative to publicUrl) to index.html head, paths already loaded by some tag are skipped, returns bool if injected into content
+func InjectLinksIntoIndex(indexFile []byte, rel string, paths []string, publicUrl string) ([]byte, bool) {
+	publicUrl = strings.TrimSuffix(publicUrl, "/")
+	doc := parseIndex(indexFile)
+
+	var links indexDocument
+	for _, path := range paths {
+		if doc.hasAsset(path, publicUrl) || links.hasAsset(path, publicUrl) {
+			continue
+		}
+		links = append(links, newTag(atom.Link, "rel", rel, "href", publicUrl+path), newLine())
+	}
+	if len(links) == 0 {
+		return indexFile, false
+	}
+
+	return doc.insert(doc.headIndex(), links...).Bytes(), true
+}
+
+// RemoveLinkFromIndex removes link tags with rel for path (relative to publicUrl) from index.html content, returns bool if removed from content
+func RemoveLinkFromIndex(indexFile []byte, rel string, path string, publicUrl string) ([]byte, bool) {
+	publicUrl = strings.TrimSuffix(publicUrl, "/")
+	doc := parseIndex(indexFile)
+
+	var kept indexDocument
+	removed := false
+	for i := 0; i < len(doc); i++ {
+		linkRel, _ := doc[i].attr("rel")
+		if url, ok := doc[i].assetPath(publicUrl); ok && doc[i].isStartTag(atom.Link) && linkRel == rel && url == path {
+			removed = true
+			// injected links are followed by new line
+			if i+1 < len(doc) && doc[i+1].Type == html.TextToken && doc[i+1].Data == "\n" {
+				i++
+			}
+			continue
+		}
+		kept = append(kept, doc[i])
+	}
+
+	if !removed {
+		return indexFile, false
+	}
+	return kept.Bytes(), true
+}
+
+// IntegrityHash is subresource integrity value of content
+func IntegrityHash(content []byte) string {
+	sum := sha512.Sum384(content)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// InjectIntegrityIntoIndex sets integrity and crossorigin attributes on script/link tags pointing to paths (relative to publicUrl) in integrity map, returns bool if injected into content
+func InjectIntegrityIntoIndex(indexFile []byte, integrity map[string]string, publicUrl string) ([]byte, bool) {
+	publicUrl = strings.TrimSuffix(publicUrl, "/")
+	doc := parseIndex(indexFile)
+	changed := false
+
+	for i := range doc {
+		path, ok := doc[i].assetPath(publicUrl)
+		if !ok {
+			continue
+		}
+		hash, ok := integrity[path]
+		if !ok {
+			continue
+		}
+
 		changed = true
-		indexFile = bytes.ReplaceAll(indexFile, []byte("%PUBLIC_URL%"), []byte(publicUrl))
+		doc[i].setAttr("integrity", hash)
+		doc[i].setAttr("crossorigin", "anonymous")
 	}
 
-	return indexFile, changed
+	if !changed {
+		return indexFile, false
+	}
+	return doc.Bytes(), true
 }
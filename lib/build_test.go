@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInjectIntegrityIntoIndexUpdatesBuildOutputTags(t *testing.T) {
+	index := []byte(`<head><link rel="stylesheet" href="https://cdn.local/app/assets/index.css?v=1" integrity="sha384-old" crossorigin/>` +
+		`<link rel="icon" href="/favicon.ico"></head><body><script type="module" src='https://cdn.local/app/assets/index.js'></script></body>`)
+	integrity := map[string]string{
+		"/assets/index.css": IntegrityHash([]byte("body{}")),
+		"/assets/index.js":  IntegrityHash([]byte("console.log(1)")),
+	}
+
+	got, changed := InjectIntegrityIntoIndex(index, integrity, "https://cdn.local/app/")
+	if !changed {
+		t.Fatal("expected index to change")
+	}
+
+	want := `<head><link rel="stylesheet" href="https://cdn.local/app/assets/index.css?v=1" integrity="` + integrity["/assets/index.css"] + `" crossorigin="anonymous"/>` +
+		`<link rel="icon" href="/favicon.ico"></head><body><script type="module" src="https://cdn.local/app/assets/index.js" integrity="` + integrity["/assets/index.js"] + `" crossorigin="anonymous"></script></body>`
+	if string(got) != want {
+		t.Fatalf("InjectIntegrityIntoIndex() = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(integrity["/assets/index.js"], "sha384-") {
+		t.Fatalf("IntegrityHash() = %q, want sha384 prefix", integrity["/assets/index.js"])
+	}
+}
+
+func TestInjectAssetsIntoIndexUsesHTMLStructure(t *testing.T) {
+	tests := []struct {
+		name  string
+		index string
+		want  string
+	}{
+		{
+			name:  "uppercase tags",
+			index: `<HTML><HEAD><TITLE>app</TITLE></HEAD><BODY></BODY></HTML>`,
+			want: `<HTML><HEAD><TITLE>app</TITLE><link rel="modulepreload" href="/assets/index.js">` + "\n" +
+				`</HEAD><BODY><script type="module" src="/assets/index.js"></script>` + "\n" + `</BODY></HTML>`,
+		},
+		{
+			name:  "missing head end and path in comment",
+			index: `<!doctype html><title>app</title><!-- /assets/index.js --><body><div id="root"></div>`,
+			want: `<!doctype html><title>app</title><!-- /assets/index.js --><link rel="modulepreload" href="/assets/index.js">` + "\n" +
+				`<body><div id="root"></div><script type="module" src="/assets/index.js"></script>` + "\n",
+		},
+		{
+			name:  "markers",
+			index: `<head><!-- nrb:head --><meta name="x"></head><body><!-- nrb:body --><script>late()</script></body>`,
+			want: `<head><link rel="modulepreload" href="/assets/index.js">` + "\n" + `<!-- nrb:head --><meta name="x"></head>` +
+				`<body><script type="module" src="/assets/index.js"></script>` + "\n" + `<!-- nrb:body --><script>late()</script></body>`,
+		},
+		{
+			name:  "script already in template",
+			index: `<head></head><body><SCRIPT type="module" src="%PUBLIC_URL%/assets/index.js?v=1"></SCRIPT></body>`,
+			want:  `<head></head><body><SCRIPT type="module" src="/assets/index.js?v=1"></SCRIPT></body>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := InjectAssetsIntoIndex([]byte(tt.index), "/assets/index.js", "", "/")
+			if string(got) != tt.want {
+				t.Fatalf("InjectAssetsIntoIndex() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
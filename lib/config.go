@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/evanw/esbuild/pkg/api"
@@ -37,10 +38,24 @@ type Config struct {
 	ResolveModules           MapFlags
 	PreloadPathsStartingWith ArrayFlags
 	Injects                  ArrayFlags
+	Interpolate              ArrayFlags
 	InlineSize               int64
 	InlineExtensions         []string
 	Loaders                  LoaderFlags
 	Splitting                bool
+	HMR                      bool
+	AutoPreload              bool
+	Prefetch                 bool
+	SRI                      bool
+	CSP                      string
+	CSPMode                  string
+	HTMLTemplate             bool
+	EnvTypes                 string
+	Proxy                    []ProxyRule
+	Pages                    []Page
+	EnvSchema                EnvSchema
+	Budgets                  Budgets
+	Duplicates               Duplicates
 }
 
 type OptionalBool struct {
@@ -92,14 +107,28 @@ type ConfigPatch struct {
 	Metafile        OptionalBool
 	TSConfigPath    OptionalString
 	Splitting       OptionalBool
+	HMR             OptionalBool
+	AutoPreload     OptionalBool
+	Prefetch        OptionalBool
+	SRI             OptionalBool
+	CSP             OptionalString
+	CSPMode         OptionalString
+	HTMLTemplate    OptionalBool
+	EnvTypes        OptionalString
 
 	AliasPackages            MapFlags
 	ResolveModules           MapFlags
 	PreloadPathsStartingWith ArrayFlags
 	Injects                  ArrayFlags
+	Interpolate              ArrayFlags
 	InlineExtensions         ArrayFlags
 	InlineSize               OptionalInt64
 	Loaders                  LoaderFlags
+	Proxy                    []ProxyRule
+	Pages                    []Page
+	EnvSchema                EnvSchema
+	Budgets                  *Budgets
+	Duplicates               *Duplicates
 }
 
 type ConfigOverrides struct {
@@ -126,14 +155,24 @@ type ConfigOverrides struct {
 	Metafile        OptionalBool
 	TSConfigPath    OptionalString
 	Splitting       OptionalBool
+	HMR             OptionalBool
+	AutoPreload     OptionalBool
+	Prefetch        OptionalBool
+	SRI             OptionalBool
+	CSP             OptionalString
+	CSPMode         OptionalString
+	HTMLTemplate    OptionalBool
+	EnvTypes        OptionalString
 
 	AliasPackages            MapFlags
 	ResolveModules           MapFlags
 	PreloadPathsStartingWith ArrayFlags
 	Injects                  ArrayFlags
+	Interpolate              ArrayFlags
 	InlineExtensions         ArrayFlags
 	InlineSize               OptionalInt64
 	Loaders                  LoaderFlags
+	Proxy                    []ProxyRule
 }
 
 type PackageJson map[string]any
@@ -156,6 +195,7 @@ func DefaultConfig() Config {
 		JSX:           api.JSXAutomatic,
 		SourceMap:     api.SourceMapLinked,
 		TSConfigPath:  "tsconfig.json",
+		CSPMode:       "meta",
 	}
 }
 
@@ -202,6 +242,14 @@ func MergeConfig(base Config, overlay ConfigPatch) Config {
 	mergeOptionalBool(&base.Metafile, overlay.Metafile)
 	mergeOptionalString(&base.TSConfigPath, overlay.TSConfigPath)
 	mergeOptionalBool(&base.Splitting, overlay.Splitting)
+	mergeOptionalBool(&base.HMR, overlay.HMR)
+	mergeOptionalBool(&base.AutoPreload, overlay.AutoPreload)
+	mergeOptionalBool(&base.Prefetch, overlay.Prefetch)
+	mergeOptionalBool(&base.SRI, overlay.SRI)
+	mergeOptionalString(&base.CSP, overlay.CSP)
+	mergeOptionalString(&base.CSPMode, overlay.CSPMode)
+	mergeOptionalBool(&base.HTMLTemplate, overlay.HTMLTemplate)
+	mergeOptionalString(&base.EnvTypes, overlay.EnvTypes)
 
 	if overlay.AliasPackages != nil {
 		base.AliasPackages = overlay.AliasPackages
@@ -215,6 +263,9 @@ func MergeConfig(base Config, overlay ConfigPatch) Config {
 	if overlay.Injects != nil {
 		base.Injects = overlay.Injects
 	}
+	if overlay.Interpolate != nil {
+		base.Interpolate = overlay.Interpolate
+	}
 	if overlay.InlineExtensions != nil {
 		base.InlineExtensions = overlay.InlineExtensions
 	}
@@ -222,6 +273,21 @@ func MergeConfig(base Config, overlay ConfigPatch) Config {
 	if overlay.Loaders != nil {
 		base.Loaders = overlay.Loaders
 	}
+	if overlay.Proxy != nil {
+		base.Proxy = overlay.Proxy
+	}
+	if overlay.Pages != nil {
+		base.Pages = overlay.Pages
+	}
+	if overlay.EnvSchema != nil {
+		base.EnvSchema = overlay.EnvSchema
+	}
+	if overlay.Budgets != nil {
+		base.Budgets = *overlay.Budgets
+	}
+	if overlay.Duplicates != nil {
+		base.Duplicates = *overlay.Duplicates
+	}
 
 	return base
 }
@@ -250,6 +316,14 @@ func ApplyOverrides(cfg *Config, overrides ConfigOverrides) {
 	mergeOptionalBool(&cfg.Metafile, overrides.Metafile)
 	mergeOptionalString(&cfg.TSConfigPath, overrides.TSConfigPath)
 	mergeOptionalBool(&cfg.Splitting, overrides.Splitting)
+	mergeOptionalBool(&cfg.HMR, overrides.HMR)
+	mergeOptionalBool(&cfg.AutoPreload, overrides.AutoPreload)
+	mergeOptionalBool(&cfg.Prefetch, overrides.Prefetch)
+	mergeOptionalBool(&cfg.SRI, overrides.SRI)
+	mergeOptionalString(&cfg.CSP, overrides.CSP)
+	mergeOptionalString(&cfg.CSPMode, overrides.CSPMode)
+	mergeOptionalBool(&cfg.HTMLTemplate, overrides.HTMLTemplate)
+	mergeOptionalString(&cfg.EnvTypes, overrides.EnvTypes)
 
 	if overrides.AliasPackages != nil {
 		cfg.AliasPackages = overrides.AliasPackages
@@ -263,6 +337,9 @@ func ApplyOverrides(cfg *Config, overrides ConfigOverrides) {
 	if overrides.Injects != nil {
 		cfg.Injects = overrides.Injects
 	}
+	if overrides.Interpolate != nil {
+		cfg.Interpolate = overrides.Interpolate
+	}
 	if overrides.InlineExtensions != nil {
 		cfg.InlineExtensions = overrides.InlineExtensions
 	}
@@ -270,6 +347,9 @@ func ApplyOverrides(cfg *Config, overrides ConfigOverrides) {
 	if overrides.Loaders != nil {
 		cfg.Loaders = overrides.Loaders
 	}
+	if overrides.Proxy != nil {
+		cfg.Proxy = overrides.Proxy
+	}
 }
 
 func mergeOptionalString(dst *string, value OptionalString) {
@@ -305,6 +385,14 @@ func mergeOptionalBool(dst *bool, value OptionalBool) {
 func ParseJsonConfig(packageJson PackageJson) (ConfigPatch, error) {
 	config := ConfigPatch{}
 
+	// CRA top level "proxy" field
+	if err := parseProxy(packageJson, "proxy", &config.Proxy); err != nil {
+		return config, err
+	}
+	if len(config.Proxy) > 0 && !config.Proxy[0].Fallback {
+		return config, errors.New("wrong 'proxy' key in 'package.json', use string, for proxy rules use 'nrb.proxy'")
+	}
+
 	raw, ok := packageJson["nrb"]
 	if !ok || raw == nil {
 		return config, nil
@@ -384,6 +472,35 @@ func ParseJsonConfig(packageJson PackageJson) (ConfigPatch, error) {
 	if err := parseOptionalBool(options, "splitting", &config.Splitting); err != nil {
 		return config, err
 	}
+	if err := parseOptionalBool(options, "hmr", &config.HMR); err != nil {
+		return config, err
+	}
+	if err := parseOptionalBool(options, "autoPreload", &config.AutoPreload); err != nil {
+		return config, err
+	}
+	if err := parseOptionalBool(options, "prefetch", &config.Prefetch); err != nil {
+		return config, err
+	}
+	if err := parseOptionalBool(options, "sri", &config.SRI); err != nil {
+		return config, err
+	}
+	if err := parseOptionalString(options, "csp", &config.CSP); err != nil {
+		return config, err
+	}
+	if err := parseOptionalString(options, "cspMode", &config.CSPMode); err != nil {
+		return config, err
+	}
+	if config.CSPMode.Set {
+		if _, err := ParseCSPMode(config.CSPMode.Value); err != nil {
+			return config, err
+		}
+	}
+	if err := parseOptionalBool(options, "htmlTemplate", &config.HTMLTemplate); err != nil {
+		return config, err
+	}
+	if err := parseOptionalString(options, "envTypes", &config.EnvTypes); err != nil {
+		return config, err
+	}
 
 	if err := parseStringMap(options, "alias", &config.AliasPackages); err != nil {
 		return config, err
@@ -397,12 +514,38 @@ func ParseJsonConfig(packageJson PackageJson) (ConfigPatch, error) {
 	if err := parseStringSlice(options, "inject", &config.Injects); err != nil {
 		return config, err
 	}
+	if err := parseStringSlice(options, "interpolate", &config.Interpolate); err != nil {
+		return config, err
+	}
+	if err := CheckGlobs(config.Interpolate); err != nil {
+		return config, err
+	}
 	if err := parseLoaderMap(options, "loaders", &config.Loaders); err != nil {
 		return config, err
 	}
 	if err := parseInline(options, &config); err != nil {
 		return config, err
 	}
+	if err := parsePages(options, "pages", &config.Pages); err != nil {
+		return config, err
+	}
+	if err := parseEnvSchema(options, "env", &config.EnvSchema); err != nil {
+		return config, err
+	}
+	if err := parseBudgets(options, "budgets", &config.Budgets); err != nil {
+		return config, err
+	}
+	if err := parseDuplicates(options, "duplicates", &config.Duplicates); err != nil {
+		return config, err
+	}
+
+	craProxy := config.Proxy
+	if err := parseProxy(options, "proxy", &config.Proxy); err != nil {
+		return config, err
+	}
+	if len(craProxy) > 0 && !slices.ContainsFunc(config.Proxy, func(rule ProxyRule) bool { return rule.Fallback }) {
+		config.Proxy = append(config.Proxy, craProxy...)
+	}
 
 	return config, nil
 }
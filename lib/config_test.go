@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/evanw/esbuild/pkg/api"
@@ -416,3 +417,162 @@ func TestParseJsonConfigPreservesStructuredOptions(t *testing.T) {
 		t.Fatalf("unexpected Splitting patch: %#v", patch.Splitting)
 	}
 }
+
+func TestParseJsonConfigReadsProxyRules(t *testing.T) {
+	patch, err := ParseJsonConfig(PackageJson{
+		"proxy": "http://localhost:5000",
+		"nrb": map[string]any{
+			"proxy": map[string]any{
+				"/api": "http://localhost:8080",
+				"/api/auth": map[string]any{
+					"target":       "https://auth.local:9000/base",
+					"pathRewrite":  map[string]any{"^/api/auth": ""},
+					"headers":      map[string]any{"X-Dev": "1"},
+					"changeOrigin": true,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ParseJsonConfig returned error: %v", err)
+	}
+
+	if got := len(patch.Proxy); got != 3 {
+		t.Fatalf("expected 3 proxy rules, got %d: %#v", got, patch.Proxy)
+	}
+	if patch.Proxy[0].Path != "/api/auth" || !patch.Proxy[0].ChangeOrigin || patch.Proxy[0].Headers["X-Dev"] != "1" {
+		t.Fatalf("expected longest path rule first, got %#v", patch.Proxy[0])
+	}
+	if patch.Proxy[1].Path != "/api" || patch.Proxy[1].Target != "http://localhost:8080" {
+		t.Fatalf("unexpected second proxy rule: %#v", patch.Proxy[1])
+	}
+	if !patch.Proxy[2].Fallback || patch.Proxy[2].Target != "http://localhost:5000" {
+		t.Fatalf("expected CRA proxy as last fallback rule, got %#v", patch.Proxy[2])
+	}
+
+	target, err := patch.Proxy[0].TargetURL("/api/auth/login", "a=1")
+	if err != nil {
+		t.Fatalf("TargetURL returned error: %v", err)
+	}
+	if got := target.String(); got != "https://auth.local:9000/base/login?a=1" {
+		t.Fatalf("TargetURL = %q, want %q", got, "https://auth.local:9000/base/login?a=1")
+	}
+}
+
+func TestProxyRuleMatchesPathPrefixOnly(t *testing.T) {
+	rule := ProxyRule{Path: "/api", Target: "http://localhost:8080"}
+
+	if !rule.Matches("/api") || !rule.Matches("/api/users") {
+		t.Fatal("expected rule to match its path prefix")
+	}
+	if rule.Matches("/apiary") || rule.Matches("/") {
+		t.Fatal("did not expect rule to match other paths")
+	}
+	if (ProxyRule{Path: "/", Fallback: true}).Matches("/api") {
+		t.Fatal("did not expect fallback rule to match by path")
+	}
+}
+
+func TestParseJsonConfigRejectsInvalidProxy(t *testing.T) {
+	tests := []struct {
+		name string
+		pkg  PackageJson
+	}{
+		{
+			name: "CRA proxy must be url",
+			pkg:  PackageJson{"proxy": "localhost:5000"},
+		},
+		{
+			name: "CRA proxy must be string",
+			pkg:  PackageJson{"proxy": map[string]any{"/api": "http://localhost:5000"}},
+		},
+		{
+			name: "rule target is required",
+			pkg: PackageJson{
+				"nrb": map[string]any{"proxy": map[string]any{"/api": map[string]any{}}},
+			},
+		},
+		{
+			name: "pathRewrite must be valid regexp",
+			pkg: PackageJson{
+				"nrb": map[string]any{"proxy": map[string]any{"/api": map[string]any{
+					"target":      "http://localhost:8080",
+					"pathRewrite": map[string]any{"(": ""},
+				}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseJsonConfig(tt.pkg); err == nil {
+				t.Fatal("expected ParseJsonConfig to fail")
+			}
+		})
+	}
+}
+
+func TestParseJsonConfigReadsPages(t *testing.T) {
+	patch, err := ParseJsonConfig(PackageJson{
+		"nrb": map[string]any{
+			"pages": map[string]any{
+				"main":  map[string]any{"entry": "index.tsx", "output": "index.html"},
+				"admin": map[string]any{"entry": "admin.tsx", "template": "admin.html"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ParseJsonConfig returned error: %v", err)
+	}
+
+	want := []Page{
+		{Name: "admin", Entry: "admin.tsx", Template: "admin.html", Output: "admin/index.html"},
+		{Name: "main", Entry: "index.tsx", Output: "index.html"},
+	}
+	if !reflect.DeepEqual(patch.Pages, want) {
+		t.Fatalf("Pages = %#v, want %#v", patch.Pages, want)
+	}
+
+	cfg := MergeConfig(DefaultConfig(), patch)
+	for urlPath, wantPage := range map[string]string{"/": "main", "/users/1": "main", "/admin": "admin", "/admin/users/1": "admin", "/administrator": "main"} {
+		page, ok := FindPage(cfg.EntryPages(), urlPath)
+		if !ok || page.Name != wantPage {
+			t.Fatalf("FindPage(%q) = %q, want %q", urlPath, page.Name, wantPage)
+		}
+	}
+}
+
+func TestEntryPagesDefaultsToEntryFileName(t *testing.T) {
+	cfg := DefaultConfig()
+
+	want := []Page{{Name: "index", Entry: "index.tsx", Output: "index.html"}}
+	if got := cfg.EntryPages(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("EntryPages() = %#v, want %#v", got, want)
+	}
+	if got := (Page{Output: "admin.html"}).Route(); got != "/admin" {
+		t.Fatalf("Route() = %q, want %q", got, "/admin")
+	}
+}
+
+func TestParseJsonConfigRejectsInvalidPages(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages any
+	}{
+		{name: "pages must be object", pages: []any{"index.tsx"}},
+		{name: "entry is required", pages: map[string]any{"admin": map[string]any{"template": "admin.html"}}},
+		{name: "output must be html", pages: map[string]any{"admin": map[string]any{"entry": "admin.tsx", "output": "admin"}}},
+		{name: "outputs must differ", pages: map[string]any{
+			"main":  map[string]any{"entry": "index.tsx", "output": "index.html"},
+			"other": map[string]any{"entry": "other.tsx", "output": "./index.html"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseJsonConfig(PackageJson{"nrb": map[string]any{"pages": tt.pages}}); err == nil {
+				t.Fatal("expected ParseJsonConfig to fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// CSP is parsed Content-Security-Policy, directives keep their order, first item of directive is its name
+type CSP [][]string
+
+// ParseCSPMode checks csp output mode
+func ParseCSPMode(value string) (string, error) {
+	switch value {
+	case "meta", "headers":
+		return value, nil
+	default:
+		return "", fmt.Errorf("wrong 'cspMode' value in 'package.json', use meta|headers")
+	}
+}
+
+// ParseCSP splits policy to directives
+func ParseCSP(policy string) CSP {
+	var csp CSP
+	for directive := range strings.SplitSeq(policy, ";") {
+		if fields := strings.Fields(directive); len(fields) > 0 {
+			fields[0] = strings.ToLower(fields[0])
+			csp = append(csp, fields)
+		}
+	}
+	return csp
+}
+
+// AddSources adds sources to directive, missing directive starts from default-src sources so policy does not get looser,
+// without both the directive is not restricted and policy stays as is
+func (csp CSP) AddSources(name string, sources ...string) CSP {
+	csp = slices.Clone(csp)
+
+	i := slices.IndexFunc(csp, func(directive []string) bool { return directive[0] == name })
+	if i == -1 {
+		d := slices.IndexFunc(csp, func(directive []string) bool { return directive[0] == "default-src" })
+		if d == -1 {
+			return csp
+		}
+		csp = append(csp, append([]string{name}, csp[d][1:]...))
+		i = len(csp) - 1
+	}
+
+	directive := slices.Clone(csp[i])
+	for _, source := range sources {
+		if !slices.Contains(directive[1:], source) {
+			// 'none' cannot be combined with other sources
+			directive = slices.DeleteFunc(directive, func(s string) bool { return s == "'none'" })
+			directive = append(directive, source)
+		}
+	}
+	csp[i] = directive
+
+	return csp
+}
+
+// Merge adds directives of other policy, directives in both get sources of both
+func (csp CSP) Merge(other CSP) CSP {
+	for _, directive := range other {
+		if slices.ContainsFunc(csp, func(d []string) bool { return d[0] == directive[0] }) {
+			csp = csp.AddSources(directive[0], directive[1:]...)
+		} else {
+			csp = append(slices.Clone(csp), slices.Clone(directive))
+		}
+	}
+	return csp
+}
+
+func (csp CSP) String() string {
+	directives := make([]string, len(csp))
+	for i, directive := range csp {
+		directives[i] = strings.Join(directive, " ")
+	}
+	return strings.Join(directives, "; ")
+}
+
+// InlineHashes returns csp sources with sha256 hashes of inline scripts and styles in index.html content
+func InlineHashes(indexFile []byte) (scripts, styles []string) {
+	doc := parseIndex(indexFile)
+	for i, t := range doc {
+		if !t.isStartTag(atom.Script) && !t.isStartTag(atom.Style) {
+			continue
+		}
+		if _, ok := t.attr("src"); ok && t.DataAtom == atom.Script {
+			continue
+		}
+
+		// script/style content is one raw text token, empty element has none
+		var content []byte
+		if i+1 < len(doc) && doc[i+1].Type == html.TextToken {
+			content = doc[i+1].raw
+		}
+		if t.DataAtom == atom.Script {
+			scripts = appendUnique(scripts, cspHash(content))
+		} else {
+			styles = appendUnique(styles, cspHash(content))
+		}
+	}
+	return scripts, styles
+}
+
+// InjectNonceIntoIndex adds nonce attribute to script and style tags in index.html content
+func InjectNonceIntoIndex(indexFile []byte, nonce string) []byte {
+	doc := parseIndex(indexFile)
+	for i, t := range doc {
+		if !t.isStartTag(atom.Script) && !t.isStartTag(atom.Style) {
+			continue
+		}
+		if _, ok := t.attr("nonce"); !ok {
+			doc[i].setAttr("nonce", nonce)
+		}
+	}
+	return doc.Bytes()
+}
+
+// CSPMetaOfIndex returns policy of csp meta tags in index.html content, empty if there are none
+func CSPMetaOfIndex(indexFile []byte) CSP {
+	var csp CSP
+	for _, t := range parseIndex(indexFile) {
+		if t.isCSPMeta() {
+			content, _ := t.attr("content")
+			csp = csp.Merge(ParseCSP(content))
+		}
+	}
+	return csp
+}
+
+// InjectCSPMetaIntoIndex adds csp meta tag as first thing in head after charset meta, so it applies to everything after
+// and charset stays in first bytes of the page, csp meta tags already in index.html are replaced, as browser would enforce all of them,
+// merge them to policy with CSPMetaOfIndex, returns bool if content changed
+func InjectCSPMetaIntoIndex(indexFile []byte, policy string) ([]byte, bool) {
+	doc := slices.DeleteFunc(parseIndex(indexFile), indexToken.isCSPMeta)
+
+	at := doc.headStartIndex()
+	if i := slices.IndexFunc(doc, indexToken.isCharsetMeta); i != -1 {
+		at = i + 1
+	}
+
+	content := doc.insert(at, newTag(atom.Meta, "http-equiv", "Content-Security-Policy", "content", policy)).Bytes()
+	return content, !bytes.Equal(content, indexFile)
+}
+
+func (t indexToken) isCSPMeta() bool {
+	httpEquiv, _ := t.attr("http-equiv")
+	return t.isStartTag(atom.Meta) && strings.EqualFold(httpEquiv, "Content-Security-Policy")
+}
+
+func (t indexToken) isCharsetMeta() bool {
+	if !t.isStartTag(atom.Meta) {
+		return false
+	}
+	_, ok := t.attr("charset")
+	httpEquiv, _ := t.attr("http-equiv")
+	return ok || strings.EqualFold(httpEquiv, "Content-Type")
+}
+
+func cspHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+func appendUnique(list []string, value string) []string {
+	if slices.Contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
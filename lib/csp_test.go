@@ -0,0 +1,66 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCSPAddSourcesKeepsPolicyStrict(t *testing.T) {
+	policy := ParseCSP("default-src 'self'; style-src 'none'; img-src *")
+
+	got := policy.AddSources("script-src", "'sha256-a'").AddSources("style-src", "'sha256-b'").String()
+	want := "default-src 'self'; style-src 'sha256-b'; img-src *; script-src 'self' 'sha256-a'"
+	if got != want {
+		t.Fatalf("AddSources() = %q, want %q", got, want)
+	}
+
+	// nothing restricts scripts without default-src, so there is nothing to allow
+	if got := ParseCSP("img-src *").AddSources("script-src", "'sha256-a'").String(); got != "img-src *" {
+		t.Fatalf("AddSources() = %q, want %q", got, "img-src *")
+	}
+}
+
+func TestInlineHashesSkipsExternalScripts(t *testing.T) {
+	index := []byte(`<head><style>body{}</style><script>window.a=1</script><script type="module" src="/assets/index.js"></script></head>`)
+
+	scripts, styles := InlineHashes(index)
+	if len(scripts) != 1 || scripts[0] != cspHash([]byte("window.a=1")) {
+		t.Fatalf("InlineHashes() scripts = %v, want hash of inline script only", scripts)
+	}
+	if len(styles) != 1 || !strings.HasPrefix(styles[0], "'sha256-") {
+		t.Fatalf("InlineHashes() styles = %v, want one sha256 source", styles)
+	}
+
+	withNonce := string(InjectNonceIntoIndex(index, "abc"))
+	if strings.Count(withNonce, ` nonce="abc"`) != 3 {
+		t.Fatalf("InjectNonceIntoIndex() = %q, want nonce on every script and style", withNonce)
+	}
+
+	withMeta, _ := InjectCSPMetaIntoIndex(index, "default-src 'self'")
+	if !strings.HasPrefix(string(withMeta), `<head><meta http-equiv="Content-Security-Policy" content="default-src &#39;self&#39;">`) {
+		t.Fatalf("InjectCSPMetaIntoIndex() = %q, want meta first in head", withMeta)
+	}
+}
+
+func TestInjectCSPMetaIntoIndexReplacesTemplateMeta(t *testing.T) {
+	index := []byte(`<head><meta charset="utf-8"><meta HTTP-EQUIV="content-security-policy" content="script-src https://cdn.example.com; img-src data:"><script>window.a=1</script></head>`)
+
+	policy := ParseCSP("default-src 'self'; script-src 'self'").Merge(CSPMetaOfIndex(index))
+	want := "default-src 'self'; script-src 'self' https://cdn.example.com; img-src data:"
+	if got := policy.String(); got != want {
+		t.Fatalf("Merge() = %q, want %q", got, want)
+	}
+
+	withMeta, _ := InjectCSPMetaIntoIndex(index, policy.String())
+	if strings.Count(strings.ToLower(string(withMeta)), "content-security-policy") != 1 {
+		t.Fatalf("InjectCSPMetaIntoIndex() = %q, want template meta replaced", withMeta)
+	}
+	// charset has to stay in first bytes of the page, long hash lists go after it
+	if !strings.HasPrefix(string(withMeta), `<head><meta charset="utf-8"><meta http-equiv="Content-Security-Policy" content="default-src &#39;self&#39;; script-src &#39;self&#39; https://cdn.example.com; img-src data:"><script>`) {
+		t.Fatalf("InjectCSPMetaIntoIndex() = %q, want merged meta right after charset", withMeta)
+	}
+
+	if again, changed := InjectCSPMetaIntoIndex(withMeta, policy.String()); changed || string(again) != string(withMeta) {
+		t.Fatalf("InjectCSPMetaIntoIndex() = %q, %v, want same policy to leave page unchanged", again, changed)
+	}
+}
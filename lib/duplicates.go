@@ -0,0 +1,153 @@
+package lib
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Duplicates configures check of packages bundled more than once
+type Duplicates struct {
+	// Fail fails build on duplicate packages not in Allow
+	Fail  bool
+	Allow []string
+}
+
+// PackageCopy is one copy of package in bundle
+type PackageCopy struct {
+	// Dir is package directory, ie. 'node_modules/a/node_modules/lodash'
+	Dir     string
+	Version string
+	// Bytes are bytes of package files in output
+	Bytes int64
+}
+
+// DuplicatePackage is package bundled from more than one directory
+type DuplicatePackage struct {
+	Name string
+	// Copies are sorted by bytes, biggest first
+	Copies []PackageCopy
+}
+
+// Bytes is size of all copies in output
+func (d DuplicatePackage) Bytes() int64 {
+	var total int64
+	for _, c := range d.Copies {
+		total += c.Bytes
+	}
+	return total
+}
+
+// Wasted is size of copies beside the biggest one
+func (d DuplicatePackage) Wasted() int64 {
+	return d.Bytes() - d.Copies[0].Bytes
+}
+
+// IsAllowed checks if package is in allowlist
+func (d Duplicates) IsAllowed(name string) bool {
+	return slices.Contains(d.Allow, name)
+}
+
+// PackageOfPath returns package name and its directory for file path in node_modules, last node_modules wins for nested copies
+func PackageOfPath(path string) (name, dir string, ok bool) {
+	i := strings.LastIndex(path, "node_modules/")
+	if i == -1 || (i > 0 && path[i-1] != '/') {
+		return "", "", false
+	}
+	start := i + len("node_modules/")
+	parts := strings.SplitN(path[start:], "/", 3)
+	if len(parts) < 2 || parts[0] == "" {
+		return "", "", false
+	}
+
+	name = parts[0]
+	if strings.HasPrefix(name, "@") {
+		if len(parts) < 3 {
+			return "", "", false
+		}
+		name += "/" + parts[1]
+	}
+	return name, path[:start+len(name)], true
+}
+
+// FindDuplicatePackages groups input paths with their bytes in output by package, returns packages with more than one copy,
+// most wasted bytes first, version reads version of package directory, inputs tree shaken out of output do not count
+func FindDuplicatePackages(inputBytes map[string]int64, version func(dir string) string) []DuplicatePackage {
+	copies := make(map[string]map[string]int64)
+	for path, bytes := range inputBytes {
+		name, dir, ok := PackageOfPath(path)
+		if !ok || bytes == 0 {
+			continue
+		}
+		if copies[name] == nil {
+			copies[name] = make(map[string]int64)
+		}
+		copies[name][dir] += bytes
+	}
+
+	var duplicates []DuplicatePackage
+	for name, dirs := range copies {
+		if len(dirs) < 2 {
+			continue
+		}
+		duplicate := DuplicatePackage{Name: name}
+		for _, dir := range slices.Sorted(maps.Keys(dirs)) {
+			duplicate.Copies = append(duplicate.Copies, PackageCopy{Dir: dir, Version: version(dir), Bytes: dirs[dir]})
+		}
+		slices.SortStableFunc(duplicate.Copies, func(a, b PackageCopy) int {
+			return cmp.Compare(b.Bytes, a.Bytes)
+		})
+		duplicates = append(duplicates, duplicate)
+	}
+
+	slices.SortFunc(duplicates, func(a, b DuplicatePackage) int {
+		if c := cmp.Compare(b.Wasted(), a.Wasted()); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return duplicates
+}
+
+func parseDuplicates(options map[string]any, key string, target **Duplicates) error {
+	value, ok := options[key]
+	if !ok {
+		return nil
+	}
+
+	rawMap, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("wrong '%s' key in 'package.json', use object with 'fail' and 'allow'", key)
+	}
+
+	duplicates := &Duplicates{}
+	for name, raw := range rawMap {
+		switch name {
+		case "fail":
+			fail, ok := raw.(bool)
+			if !ok {
+				return fmt.Errorf("wrong '%s.fail' key in 'package.json', use bool", key)
+			}
+			duplicates.Fail = fail
+		case "allow":
+			rawAllow, ok := raw.([]any)
+			if !ok {
+				return fmt.Errorf("wrong '%s.allow' key in 'package.json', use array of package names", key)
+			}
+			for _, pkg := range rawAllow {
+				name, ok := pkg.(string)
+				if !ok || name == "" {
+					return fmt.Errorf("wrong '%s.allow' key in 'package.json', use array of package names", key)
+				}
+				duplicates.Allow = append(duplicates.Allow, name)
+			}
+		default:
+			return fmt.Errorf("wrong '%s.%s' key in 'package.json', use fail|allow", key, name)
+		}
+	}
+
+	*target = duplicates
+	return nil
+}
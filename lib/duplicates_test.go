@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackageOfPath(t *testing.T) {
+	tests := map[string][2]string{
+		"node_modules/lodash/lodash.js":                           {"lodash", "node_modules/lodash"},
+		"../node_modules/@babel/runtime/helpers/extends.js":       {"@babel/runtime", "../node_modules/@babel/runtime"},
+		"node_modules/a/node_modules/lodash/get.js":               {"lodash", "node_modules/a/node_modules/lodash"},
+		"node_modules/.pnpm/dayjs@1.11.0/node_modules/dayjs/x.js": {"dayjs", "node_modules/.pnpm/dayjs@1.11.0/node_modules/dayjs"},
+		"src/node_modules.ts":                                     {"", ""},
+		"src/my_node_modules/x/y.js":                              {"", ""},
+		"node_modules/@scope/index.js":                            {"", ""},
+	}
+
+	for path, want := range tests {
+		name, dir, _ := PackageOfPath(path)
+		if name != want[0] || dir != want[1] {
+			t.Errorf("PackageOfPath(%q) = %q, %q, want %q, %q", path, name, dir, want[0], want[1])
+		}
+	}
+}
+
+func TestFindDuplicatePackagesGroupsCopiesByPackage(t *testing.T) {
+	inputs := map[string]int64{
+		"src/index.tsx":                              100,
+		"node_modules/lodash/get.js":                 300,
+		"node_modules/lodash/set.js":                 200,
+		"node_modules/a/node_modules/lodash/get.js":  250,
+		"node_modules/b/node_modules/lodash/get.js":  0,
+		"node_modules/dayjs/index.js":                50,
+		"node_modules/c/node_modules/dayjs/index.js": 40,
+		"node_modules/react/index.js":                1000,
+	}
+	versions := map[string]string{
+		"node_modules/lodash":                "4.17.21",
+		"node_modules/a/node_modules/lodash": "4.17.15",
+		"node_modules/dayjs":                 "1.11.0",
+		"node_modules/c/node_modules/dayjs":  "1.11.0",
+	}
+
+	got := FindDuplicatePackages(inputs, func(dir string) string { return versions[dir] })
+	want := []DuplicatePackage{
+		{Name: "lodash", Copies: []PackageCopy{
+			{Dir: "node_modules/lodash", Version: "4.17.21", Bytes: 500},
+			{Dir: "node_modules/a/node_modules/lodash", Version: "4.17.15", Bytes: 250},
+		}},
+		{Name: "dayjs", Copies: []PackageCopy{
+			{Dir: "node_modules/dayjs", Version: "1.11.0", Bytes: 50},
+			{Dir: "node_modules/c/node_modules/dayjs", Version: "1.11.0", Bytes: 40},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindDuplicatePackages() = %+v, want %+v", got, want)
+	}
+	if got[0].Bytes() != 750 || got[0].Wasted() != 250 {
+		t.Fatalf("lodash bytes = %d, wasted = %d, want 750 and 250", got[0].Bytes(), got[0].Wasted())
+	}
+}
+
+func TestParseDuplicates(t *testing.T) {
+	patch, err := ParseJsonConfig(PackageJson{"nrb": map[string]any{"duplicates": map[string]any{
+		"fail":  true,
+		"allow": []any{"tslib", "@babel/runtime"},
+	}}})
+	if err != nil {
+		t.Fatalf("ParseJsonConfig() returned error: %v", err)
+	}
+	want := &Duplicates{Fail: true, Allow: []string{"tslib", "@babel/runtime"}}
+	if !reflect.DeepEqual(patch.Duplicates, want) {
+		t.Fatalf("Duplicates = %+v, want %+v", patch.Duplicates, want)
+	}
+
+	for name, duplicates := range map[string]any{
+		"not an object": true,
+		"bad fail":      map[string]any{"fail": "yes"},
+		"bad allow":     map[string]any{"allow": "lodash"},
+		"unknown key":   map[string]any{"deny": []any{"lodash"}},
+	} {
+		if _, err := ParseJsonConfig(PackageJson{"nrb": map[string]any{"duplicates": duplicates}}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
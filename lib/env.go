@@ -0,0 +1,205 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var envKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// ParseEnv parses .env file content into env, later lines and files override earlier ones, returns keys set by the content
+//
+// unquoted and double quoted values expand $VAR, ${VAR}, ${VAR:-default} (unset or empty) and ${VAR-default} (unset),
+// variables are looked up in fixed first (ie. process environment, which wins over files), then in env, \$ keeps the dollar,
+// single quoted values are taken as they are
+func ParseEnv(contents []byte, env map[string]string, fixed func(name string) (string, bool)) ([]string, error) {
+	lookup := func(name string) (string, bool) {
+		if value, ok := fixed(name); ok {
+			return value, true
+		}
+		value, ok := env[name]
+		return value, ok
+	}
+
+	var keys []string
+	src := strings.ReplaceAll(string(contents), "\r\n", "\n")
+	line := 0
+	for len(src) > 0 {
+		line++
+		var current string
+		current, src, _ = strings.Cut(src, "\n")
+
+		trimmed := strings.TrimSpace(current)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		key, rest, ok := strings.Cut(strings.TrimPrefix(trimmed, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envKey.MatchString(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=value", line)
+		}
+		rest = strings.TrimLeft(rest, " \t")
+
+		var value string
+		switch {
+		case strings.HasPrefix(rest, "'"):
+			end := strings.Index(rest[1:], "'")
+			for end == -1 && len(src) > 0 {
+				// multiline value
+				var next string
+				next, src, _ = strings.Cut(src, "\n")
+				rest += "\n" + next
+				line++
+				end = strings.Index(rest[1:], "'")
+			}
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value of '%s'", line, key)
+			}
+			value = rest[1 : end+1]
+		case strings.HasPrefix(rest, `"`):
+			end := closingQuote(rest)
+			for end == -1 && len(src) > 0 {
+				var next string
+				next, src, _ = strings.Cut(src, "\n")
+				rest += "\n" + next
+				line++
+				end = closingQuote(rest)
+			}
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value of '%s'", line, key)
+			}
+			expanded, err := ExpandEnv(unescapeDoubleQuoted(rest[1:end]), lookup)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: wrong value of '%s': %w", line, key, err)
+			}
+			value = expanded
+		default:
+			// inline comment needs space before #
+			if i := strings.Index(rest, " #"); i != -1 {
+				rest = rest[:i]
+			}
+			expanded, err := ExpandEnv(strings.TrimSpace(rest), lookup)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: wrong value of '%s': %w", line, key, err)
+			}
+			value = expanded
+		}
+
+		env[key] = value
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ExpandEnv expands $VAR, ${VAR}, ${VAR:-default} and ${VAR-default} in value, \$ keeps the dollar, unknown variables are empty
+func ExpandEnv(value string, lookup func(name string) (string, bool)) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && i+1 < len(value) && value[i+1] == '$':
+			b.WriteByte('$')
+			i++
+		case c == '$' && i+1 < len(value) && value[i+1] == '{':
+			end := closingBrace(value[i:])
+			if end == -1 {
+				return "", fmt.Errorf("unterminated '${' in '%s'", value)
+			}
+			expr := value[i+2 : i+end]
+			i += end
+
+			name, fallback, hasFallback := expr, "", false
+			emptyFallback := false
+			if n, f, ok := strings.Cut(expr, ":-"); ok {
+				name, fallback, hasFallback, emptyFallback = n, f, true, true
+			} else if n, f, ok := strings.Cut(expr, "-"); ok {
+				name, fallback, hasFallback = n, f, true
+			}
+			if !envKey.MatchString(name) {
+				return "", fmt.Errorf("wrong variable name '%s' in '%s'", name, value)
+			}
+
+			resolved, ok := lookup(name)
+			if hasFallback && (!ok || (emptyFallback && resolved == "")) {
+				var err error
+				if resolved, err = ExpandEnv(fallback, lookup); err != nil {
+					return "", err
+				}
+			}
+			b.WriteString(resolved)
+		case c == '$' && i+1 < len(value) && isEnvNameStart(value[i+1]):
+			end := i + 1
+			for end < len(value) && (isEnvNameStart(value[end]) || (value[end] >= '0' && value[end] <= '9')) {
+				end++
+			}
+			resolved, _ := lookup(value[i+1 : end])
+			b.WriteString(resolved)
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// closingBrace returns index of brace closing ${ at start of value, defaults can have nested ${}, -1 if there is none
+func closingBrace(value string) int {
+	depth := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isEnvNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// closingQuote returns index of double quote ending value starting with double quote, -1 if there is none
+func closingQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeDoubleQuoted resolves \n, \r, \t, \" and \\ escapes, \$ is left for ExpandEnv
+func unescapeDoubleQuoted(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\':
+			b.WriteByte(value[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package lib
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvExpandsVariables(t *testing.T) {
+	fixed := map[string]string{"HOME": "/home/me", "EMPTY": ""}
+	env := map[string]string{"FROM_EARLIER_FILE": "earlier"}
+
+	contents := `# comment
+export HOST=localhost
+URL=http://${HOST}:${PORT:-3000}/api # inline comment
+HOME=/ignored
+DIR=$HOME/app
+EARLY="${FROM_EARLIER_FILE}"
+EMPTY_DEFAULT=${EMPTY:-empty}
+UNSET_DEFAULT=${EMPTY-unset}
+NESTED=${MISSING:-${HOST}}
+DOLLAR=\$HOST
+QUOTED="line\nnext \"quoted\" $HOST"
+RAW='$HOST ${HOST}'
+MULTI="a
+b"
+`
+	keys, err := ParseEnv([]byte(contents), env, func(name string) (string, bool) {
+		value, ok := fixed[name]
+		return value, ok
+	})
+	if err != nil {
+		t.Fatalf("ParseEnv() returned error: %v", err)
+	}
+
+	want := map[string]string{
+		"FROM_EARLIER_FILE": "earlier",
+		"HOST":              "localhost",
+		"URL":               "http://localhost:3000/api",
+		"HOME":              "/ignored",
+		"DIR":               "/home/me/app",
+		"EARLY":             "earlier",
+		"EMPTY_DEFAULT":     "empty",
+		"UNSET_DEFAULT":     "",
+		"NESTED":            "localhost",
+		"DOLLAR":            "$HOST",
+		"QUOTED":            "line\nnext \"quoted\" localhost",
+		"RAW":               "$HOST ${HOST}",
+		"MULTI":             "a\nb",
+	}
+	if !maps.Equal(env, want) {
+		t.Fatalf("ParseEnv() = %q, want %q", env, want)
+	}
+	if len(keys) != len(want)-1 || keys[0] != "HOST" {
+		t.Fatalf("ParseEnv() keys = %v, want keys of the content", keys)
+	}
+}
+
+func TestParseEnvReportsLine(t *testing.T) {
+	none := func(string) (string, bool) { return "", false }
+
+	for contents, want := range map[string]string{
+		"A=1\nnot a variable":   "line 2",
+		"A=1\nB=\"unterminated": "line 2: unterminated quoted value of 'B'",
+		"A=${B":                 "line 1: wrong value of 'A'",
+	} {
+		_, err := ParseEnv([]byte(contents), map[string]string{}, none)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("ParseEnv(%q) error = %v, want it to contain %q", contents, err, want)
+		}
+	}
+}
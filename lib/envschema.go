@@ -0,0 +1,198 @@
+package lib
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// EnvVar is env variable described in 'env' schema
+type EnvVar struct {
+	Name string
+	// Type is string|number|bool|url|enum
+	Type string
+	// Values are allowed values of enum
+	Values     []string
+	Default    string
+	HasDefault bool
+	Required   bool
+	// RequiredModes makes variable required only in these modes
+	RequiredModes []string
+}
+
+// EnvSchema is list of env variables sorted by name
+type EnvSchema []EnvVar
+
+// RequiredIn checks if variable must be set in mode
+func (v EnvVar) RequiredIn(mode string) bool {
+	return v.Required || slices.Contains(v.RequiredModes, mode)
+}
+
+// TSType is typescript type of the variable value, enum and bool get union of literals
+func (v EnvVar) TSType() string {
+	switch v.Type {
+	case "enum":
+		return TSStringUnion(v.Values)
+	case "bool":
+		return TSStringUnion([]string{"true", "false"})
+	default:
+		return "string"
+	}
+}
+
+// check returns problem with value, empty if value is fine
+func (v EnvVar) check(value string) string {
+	switch v.Type {
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Sprintf("%s '%s' is not number", v.Name, value)
+		}
+	case "bool":
+		if value != "true" && value != "false" {
+			return fmt.Sprintf("%s '%s' is not bool, use true|false", v.Name, value)
+		}
+	case "url":
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Sprintf("%s '%s' is not absolute url", v.Name, value)
+		}
+	case "enum":
+		if !slices.Contains(v.Values, value) {
+			return fmt.Sprintf("%s '%s' is not one of %s", v.Name, value, strings.Join(v.Values, "|"))
+		}
+	}
+	return ""
+}
+
+// Check validates env variables for mode, returns defaults of unset variables and list of missing or malformed ones
+func (schema EnvSchema) Check(mode string, lookup func(name string) (string, bool)) (defaults map[string]string, problems []string) {
+	defaults = make(map[string]string)
+	for _, v := range schema {
+		value, ok := lookup(v.Name)
+		if !ok && v.HasDefault {
+			defaults[v.Name] = v.Default
+			value, ok = v.Default, true
+		}
+
+		if !ok || value == "" {
+			switch {
+			case v.Required:
+				problems = append(problems, fmt.Sprintf("%s is required", v.Name))
+			case v.RequiredIn(mode):
+				problems = append(problems, fmt.Sprintf("%s is required in %s mode", v.Name, mode))
+			}
+			continue
+		}
+
+		if problem := v.check(value); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	return defaults, problems
+}
+
+func parseEnvSchema(options map[string]any, key string, target *EnvSchema) error {
+	value, ok := options[key]
+	if !ok {
+		return nil
+	}
+
+	rawMap, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("wrong '%s' key in 'package.json', use object", key)
+	}
+
+	schema := make(EnvSchema, 0, len(rawMap))
+	for name, rawVar := range rawMap {
+		v := EnvVar{Name: name, Type: "string"}
+
+		switch raw := rawVar.(type) {
+		case string:
+			v.Type = raw
+		case map[string]any:
+			if err := parseEnvVar(raw, key, &v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("wrong '%s.%s' key in 'package.json', use type string or object with 'type', 'values', 'default' and 'required'", key, name)
+		}
+
+		switch v.Type {
+		case "string", "number", "bool", "url":
+		case "enum":
+			if len(v.Values) == 0 {
+				return fmt.Errorf("wrong '%s.%s.values' key in 'package.json', enum needs array of values", key, name)
+			}
+		default:
+			return fmt.Errorf("wrong '%s.%s.type' key in 'package.json', use string|number|bool|url|enum", key, name)
+		}
+
+		if v.HasDefault {
+			if problem := v.check(v.Default); problem != "" {
+				return fmt.Errorf("wrong '%s.%s.default' key in 'package.json', %s", key, name, problem)
+			}
+		}
+
+		schema = append(schema, v)
+	}
+
+	slices.SortFunc(schema, func(a, b EnvVar) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	*target = schema
+	return nil
+}
+
+func parseEnvVar(raw map[string]any, key string, v *EnvVar) error {
+	if rawType, ok := raw["type"]; ok {
+		typeName, ok := rawType.(string)
+		if !ok {
+			return fmt.Errorf("wrong '%s.%s.type' key in 'package.json', use string|number|bool|url|enum", key, v.Name)
+		}
+		v.Type = typeName
+	}
+
+	if rawValues, ok := raw["values"]; ok {
+		values, ok := rawValues.([]any)
+		if !ok {
+			return fmt.Errorf("wrong '%s.%s.values' key in 'package.json', use array", key, v.Name)
+		}
+		for _, value := range values {
+			v.Values = append(v.Values, fmt.Sprintf("%v", value))
+		}
+		if _, ok := raw["type"]; !ok {
+			v.Type = "enum"
+		}
+	}
+
+	if rawDefault, ok := raw["default"]; ok {
+		switch d := rawDefault.(type) {
+		case string:
+			v.Default = d
+		case float64:
+			v.Default = strconv.FormatFloat(d, 'f', -1, 64)
+		case bool:
+			v.Default = strconv.FormatBool(d)
+		default:
+			return fmt.Errorf("wrong '%s.%s.default' key in 'package.json', use string, number or bool", key, v.Name)
+		}
+		v.HasDefault = true
+	}
+
+	if rawRequired, ok := raw["required"]; ok {
+		switch r := rawRequired.(type) {
+		case bool:
+			v.Required = r
+		case []any:
+			for _, mode := range r {
+				v.RequiredModes = append(v.RequiredModes, fmt.Sprintf("%v", mode))
+			}
+		default:
+			return fmt.Errorf("wrong '%s.%s.required' key in 'package.json', use bool or array of modes", key, v.Name)
+		}
+	}
+
+	return nil
+}
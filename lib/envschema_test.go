@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvSchema(t *testing.T) {
+	patch, err := ParseJsonConfig(PackageJson{"nrb": map[string]any{"env": map[string]any{
+		"REACT_APP_API_URL": map[string]any{"type": "url", "required": true},
+		"REACT_APP_TIMEOUT": map[string]any{"type": "number", "default": float64(5000)},
+		"REACT_APP_THEME":   map[string]any{"values": []any{"light", "dark"}, "default": "light"},
+		"REACT_APP_SENTRY":  map[string]any{"required": []any{"production", "staging"}},
+		"REACT_APP_DEBUG":   "bool",
+	}}})
+	if err != nil {
+		t.Fatalf("ParseJsonConfig() returned error: %v", err)
+	}
+
+	want := EnvSchema{
+		{Name: "REACT_APP_API_URL", Type: "url", Required: true},
+		{Name: "REACT_APP_DEBUG", Type: "bool"},
+		{Name: "REACT_APP_SENTRY", Type: "string", RequiredModes: []string{"production", "staging"}},
+		{Name: "REACT_APP_THEME", Type: "enum", Values: []string{"light", "dark"}, Default: "light", HasDefault: true},
+		{Name: "REACT_APP_TIMEOUT", Type: "number", Default: "5000", HasDefault: true},
+	}
+	if !reflect.DeepEqual(patch.EnvSchema, want) {
+		t.Fatalf("EnvSchema = %+v, want %+v", patch.EnvSchema, want)
+	}
+}
+
+func TestParseEnvSchemaRejectsInvalidVariables(t *testing.T) {
+	tests := map[string]any{
+		"unknown type":  map[string]any{"X": map[string]any{"type": "date"}},
+		"enum values":   map[string]any{"X": map[string]any{"type": "enum"}},
+		"bad default":   map[string]any{"X": map[string]any{"type": "number", "default": "many"}},
+		"bad required":  map[string]any{"X": map[string]any{"required": "yes"}},
+		"not an object": []any{"X"},
+	}
+
+	for name, env := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseJsonConfig(PackageJson{"nrb": map[string]any{"env": env}}); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestEnvSchemaCheckListsMissingAndMalformedVariables(t *testing.T) {
+	schema := EnvSchema{
+		{Name: "API_URL", Type: "url", Required: true},
+		{Name: "DEBUG", Type: "bool"},
+		{Name: "SENTRY", Type: "string", RequiredModes: []string{"production"}},
+		{Name: "THEME", Type: "enum", Values: []string{"light", "dark"}, Default: "light", HasDefault: true},
+		{Name: "TIMEOUT", Type: "number"},
+	}
+	env := map[string]string{"API_URL": "", "DEBUG": "yes", "TIMEOUT": "10s"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	defaults, problems := schema.Check("production", lookup)
+	if want := map[string]string{"THEME": "light"}; !reflect.DeepEqual(defaults, want) {
+		t.Fatalf("Check() defaults = %v, want %v", defaults, want)
+	}
+	want := []string{
+		"API_URL is required",
+		"DEBUG 'yes' is not bool, use true|false",
+		"SENTRY is required in production mode",
+		"TIMEOUT '10s' is not number",
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Fatalf("Check() problems =\n%s\nwant\n%s", strings.Join(problems, "\n"), strings.Join(want, "\n"))
+	}
+
+	env = map[string]string{"API_URL": "https://api.example.com", "THEME": "dark"}
+	if _, problems = schema.Check("development", lookup); len(problems) != 0 {
+		t.Fatalf("Check() problems = %v, want none", problems)
+	}
+}
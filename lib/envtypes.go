@@ -0,0 +1,109 @@
+package lib
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// EnvTypes returns typescript declarations of env variables in esbuild define, types overrides declared type by variable name,
+// optional variables are not defined now, but can be, so they are declared as optional everywhere
+//
+// process.env.X goes to NodeJS.ProcessEnv, import.meta.env.X to ImportMetaEnv and import.meta.X to ImportMeta
+func EnvTypes(define map[string]string, types map[string]string, optional []string) []byte {
+	processEnv := make(map[string]string)
+	metaEnv := make(map[string]string)
+	meta := make(map[string]string)
+
+	for key, value := range define {
+		var target map[string]string
+		var name string
+		switch {
+		case strings.HasPrefix(key, "import.meta.env."):
+			target, name = metaEnv, strings.TrimPrefix(key, "import.meta.env.")
+		case strings.HasPrefix(key, "import.meta."):
+			target, name = meta, strings.TrimPrefix(key, "import.meta.")
+		case strings.HasPrefix(key, "process.env."):
+			target, name = processEnv, strings.TrimPrefix(key, "process.env.")
+		default:
+			continue
+		}
+
+		tsType := defineType(value)
+		if tsType == "" {
+			continue
+		}
+		if override, ok := types[name]; ok && tsType == "string" {
+			tsType = override
+		}
+		// @types/node has string index signature on process.env, other types would clash with it
+		if strings.HasPrefix(key, "process.env.") && tsType == "boolean" {
+			continue
+		}
+		target[name] = tsType
+	}
+	for _, name := range optional {
+		tsType, ok := types[name]
+		if !ok {
+			tsType = "string"
+		}
+		for _, target := range []map[string]string{processEnv, metaEnv, meta} {
+			if _, ok := target[name]; !ok {
+				target[name+"?"] = tsType
+			}
+		}
+	}
+	meta["env"] = "ImportMetaEnv"
+
+	var b strings.Builder
+	b.WriteString("// Code generated by nrb, DO NOT EDIT.\n\n")
+	b.WriteString("declare namespace NodeJS {\n  interface ProcessEnv {\n")
+	writeTSProperties(&b, processEnv, "    ")
+	b.WriteString("  }\n}\n\n")
+	b.WriteString("interface ImportMetaEnv {\n")
+	writeTSProperties(&b, metaEnv, "  ")
+	b.WriteString("}\n\n")
+	b.WriteString("interface ImportMeta {\n")
+	writeTSProperties(&b, meta, "  ")
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+// TSStringUnion is typescript union of string literals
+func TSStringUnion(values []string) string {
+	literals := make([]string, len(values))
+	for i, value := range values {
+		literals[i] = strconv.Quote(value)
+	}
+	return strings.Join(literals, " | ")
+}
+
+// defineType is typescript type of esbuild define value, empty for values not declared
+func defineType(value string) string {
+	switch {
+	case value == "true" || value == "false":
+		return "boolean"
+	case strings.HasPrefix(value, `"`):
+		return "string"
+	default:
+		return ""
+	}
+}
+
+func writeTSProperties(b *strings.Builder, properties map[string]string, indent string) {
+	for _, name := range slices.Sorted(maps.Keys(properties)) {
+		key, isOptional := strings.CutSuffix(name, "?")
+		if !tsIdentifier.MatchString(key) {
+			key = strconv.Quote(key)
+		}
+		if isOptional {
+			key += "?"
+		}
+		fmt.Fprintf(b, "%sreadonly %s: %s;\n", indent, key, properties[name])
+	}
+}
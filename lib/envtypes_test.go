@@ -0,0 +1,54 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvTypesDeclaresDefinedVariables(t *testing.T) {
+	define := map[string]string{
+		"process.env.NODE_ENV":          `"production"`,
+		"process.env.FAST_REFRESH":      "false",
+		"process.env.REACT_APP_API":     `"/api"`,
+		"process.env":                   "{}",
+		"import.meta.env":               `{"MODE":"production"}`,
+		"import.meta.env.MODE":          `"production"`,
+		"import.meta.env.DEV":           "false",
+		"import.meta.env.REACT_APP_API": `"/api"`,
+		"import.meta.REACT_APP_API":     `"/api"`,
+		"import.meta":                   "{}",
+	}
+
+	types := map[string]string{"NODE_ENV": TSStringUnion([]string{"development", "production"}), "REACT_APP_THEME": TSStringUnion([]string{"light", "dark"})}
+	got := string(EnvTypes(define, types, []string{"REACT_APP_THEME"}))
+	want := `// Code generated by nrb, DO NOT EDIT.
+
+declare namespace NodeJS {
+  interface ProcessEnv {
+    readonly NODE_ENV: "development" | "production";
+    readonly REACT_APP_API: string;
+    readonly REACT_APP_THEME?: "light" | "dark";
+  }
+}
+
+interface ImportMetaEnv {
+  readonly DEV: boolean;
+  readonly MODE: string;
+  readonly REACT_APP_API: string;
+  readonly REACT_APP_THEME?: "light" | "dark";
+}
+
+interface ImportMeta {
+  readonly REACT_APP_API: string;
+  readonly REACT_APP_THEME?: "light" | "dark";
+  readonly env: ImportMetaEnv;
+}
+`
+	if got != want {
+		t.Fatalf("EnvTypes() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := string(EnvTypes(map[string]string{"process.env.REACT_APP_A-B": `"x"`}, nil, nil)); !strings.Contains(got, `readonly "REACT_APP_A-B": string;`) {
+		t.Fatalf("EnvTypes() = %s, want quoted property name", got)
+	}
+}
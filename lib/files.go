@@ -10,6 +10,11 @@ import (
 
 // CopyDir copies the content of src to dst. src should be a full path.
 func CopyDir(dst, src string) error {
+	return CopyDirInterpolated(dst, src, nil, nil)
+}
+
+// CopyDirInterpolated copies the content of src to dst like CopyDir, files matching globs get %NAME% placeholders replaced with vars
+func CopyDirInterpolated(dst, src string, globs []string, vars map[string]string) error {
 	return filepath.Walk(src, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -36,6 +41,16 @@ func CopyDir(dst, src string) error {
 			return nil
 		}
 
+		// interpolate matching files in memory
+		if rel, err := filepath.Rel(src, path); err == nil && MatchesGlobs(filepath.ToSlash(rel), globs) {
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			contents, _ = InterpolateVars(contents, vars)
+			return os.WriteFile(outpath, contents, info.Mode())
+		}
+
 		// copy contents of regular file efficiently
 
 		// open input
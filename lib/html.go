@@ -0,0 +1,176 @@
+package lib
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// markers in index.html template telling where injected tags go
+const headMarker = "nrb:head"
+const bodyMarker = "nrb:body"
+
+// indexToken is one token of index.html, raw keeps original bytes so untouched parts of the html stay as they were
+type indexToken struct {
+	html.Token
+	raw []byte
+}
+
+type indexDocument []indexToken
+
+func parseIndex(indexFile []byte) indexDocument {
+	var doc indexDocument
+	z := html.NewTokenizer(bytes.NewReader(indexFile))
+	for {
+		if z.Next() == html.ErrorToken {
+			return doc
+		}
+		// raw gets changed by Token, so copy it first
+		raw := slices.Clone(z.Raw())
+		doc = append(doc, indexToken{Token: z.Token(), raw: raw})
+	}
+}
+
+func (doc indexDocument) Bytes() []byte {
+	var b bytes.Buffer
+	for _, t := range doc {
+		b.Write(t.raw)
+	}
+	return b.Bytes()
+}
+
+// newTag makes start tag token, its raw is rendered from attributes
+func newTag(name atom.Atom, attrs ...string) indexToken {
+	t := html.Token{Type: html.StartTagToken, DataAtom: name, Data: name.String()}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		t.Attr = append(t.Attr, html.Attribute{Key: attrs[i], Val: attrs[i+1]})
+	}
+	return indexToken{Token: t, raw: []byte(t.String())}
+}
+
+func newEndTag(name atom.Atom) indexToken {
+	t := html.Token{Type: html.EndTagToken, DataAtom: name, Data: name.String()}
+	return indexToken{Token: t, raw: []byte(t.String())}
+}
+
+func newLine() indexToken {
+	return indexToken{Token: html.Token{Type: html.TextToken, Data: "\n"}, raw: []byte("\n")}
+}
+
+// setAttr sets tag attribute and renders the tag again
+func (t *indexToken) setAttr(key, val string) {
+	i := slices.IndexFunc(t.Attr, func(attr html.Attribute) bool { return attr.Namespace == "" && attr.Key == key })
+	if i == -1 {
+		t.Attr = append(t.Attr, html.Attribute{Key: key, Val: val})
+	} else {
+		t.Attr[i].Val = val
+	}
+	t.raw = []byte(t.String())
+}
+
+func (t indexToken) attr(key string) (string, bool) {
+	for _, attr := range t.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func (t indexToken) isStartTag(name atom.Atom) bool {
+	return (t.Type == html.StartTagToken || t.Type == html.SelfClosingTagToken) && t.DataAtom == name
+}
+
+func (t indexToken) isEndTag(name atom.Atom) bool {
+	return t.Type == html.EndTagToken && t.DataAtom == name
+}
+
+func (t indexToken) isMarker(marker string) bool {
+	return t.Type == html.CommentToken && strings.TrimSpace(t.Data) == marker
+}
+
+// assetPath returns path loaded by script src or link href, without publicUrl, query and hash
+func (t indexToken) assetPath(publicUrl string) (string, bool) {
+	var url string
+	var ok bool
+	switch {
+	case t.isStartTag(atom.Script):
+		url, ok = t.attr("src")
+	case t.isStartTag(atom.Link):
+		url, ok = t.attr("href")
+	}
+	if !ok || url == "" {
+		return "", false
+	}
+
+	url = strings.TrimPrefix(url, "%PUBLIC_URL%")
+	if publicUrl != "" {
+		url = strings.TrimPrefix(url, publicUrl)
+	}
+	if i := strings.IndexAny(url, "?#"); i != -1 {
+		url = url[:i]
+	}
+	return url, true
+}
+
+// hasAsset checks if some script or link tag already loads path
+func (doc indexDocument) hasAsset(path, publicUrl string) bool {
+	return slices.ContainsFunc(doc, func(t indexToken) bool {
+		url, ok := t.assetPath(publicUrl)
+		return ok && url == path
+	})
+}
+
+// headIndex is where head tags go: <!-- nrb:head --> marker, else end of head, else before body, else after html start
+func (doc indexDocument) headIndex() int {
+	if i := slices.IndexFunc(doc, func(t indexToken) bool { return t.isMarker(headMarker) }); i != -1 {
+		return i
+	}
+	if i := slices.IndexFunc(doc, func(t indexToken) bool { return t.isEndTag(atom.Head) }); i != -1 {
+		return i
+	}
+	if i := slices.IndexFunc(doc, func(t indexToken) bool { return t.isStartTag(atom.Body) }); i != -1 {
+		return i
+	}
+	return doc.afterStart()
+}
+
+// bodyIndex is where body tags go: <!-- nrb:body --> marker, else end of body, else end of html, else end of document
+func (doc indexDocument) bodyIndex() int {
+	if i := slices.IndexFunc(doc, func(t indexToken) bool { return t.isMarker(bodyMarker) }); i != -1 {
+		return i
+	}
+	if i := slices.IndexFunc(doc, func(t indexToken) bool { return t.isEndTag(atom.Body) }); i != -1 {
+		return i
+	}
+	if i := slices.IndexFunc(doc, func(t indexToken) bool { return t.isEndTag(atom.Html) }); i != -1 {
+		return i
+	}
+	return len(doc)
+}
+
+// headStartIndex is first position in head, for tags that need to go before everything else
+func (doc indexDocument) headStartIndex() int {
+	if i := slices.IndexFunc(doc, func(t indexToken) bool { return t.isStartTag(atom.Head) }); i != -1 {
+		return i + 1
+	}
+	return doc.afterStart()
+}
+
+// afterStart is position after doctype and html start tag
+func (doc indexDocument) afterStart() int {
+	if i := slices.IndexFunc(doc, func(t indexToken) bool { return t.isStartTag(atom.Html) }); i != -1 {
+		return i + 1
+	}
+	if i := slices.IndexFunc(doc, func(t indexToken) bool { return t.Type == html.DoctypeToken }); i != -1 {
+		return i + 1
+	}
+	return 0
+}
+
+func (doc indexDocument) insert(at int, tokens ...indexToken) indexDocument {
+	return slices.Insert(doc, at, tokens...)
+}
@@ -92,6 +92,9 @@ func PipedFileServer(baseDir string, pipe http.HandlerFunc) http.HandlerFunc {
 		nfrw := &NotFoundRedirectRespWr{ResponseWriter: w}
 		h.ServeHTTP(nfrw, r)
 		if nfrw.status == 404 {
+			// http.Error of the miss set these on shared header, pipe would serve everything as nosniff text
+			w.Header().Del("Content-Type")
+			w.Header().Del("X-Content-Type-Options")
 			pipe(w, r)
 		}
 	}
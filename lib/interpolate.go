@@ -0,0 +1,49 @@
+package lib
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var varPlaceholder = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_]*)%`)
+
+// InterpolateVars replaces %NAME% placeholders with values from vars, unknown names stay as they are, returns bool if replaced
+func InterpolateVars(content []byte, vars map[string]string) ([]byte, bool) {
+	changed := false
+	content = varPlaceholder.ReplaceAllFunc(content, func(placeholder []byte) []byte {
+		value, ok := vars[string(placeholder[1:len(placeholder)-1])]
+		if !ok {
+			return placeholder
+		}
+		changed = true
+		return []byte(value)
+	})
+	return content, changed
+}
+
+// MatchesGlobs checks if slash separated path relative to static dir matches some glob, glob without slash matches file name in any dir
+func MatchesGlobs(relPath string, globs []string) bool {
+	relPath = strings.TrimPrefix(relPath, "/")
+	for _, glob := range globs {
+		name := relPath
+		if !strings.Contains(glob, "/") {
+			name = path.Base(relPath)
+		}
+		if ok, _ := path.Match(strings.TrimPrefix(glob, "/"), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckGlobs checks globs syntax
+func CheckGlobs(globs []string) error {
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("wrong glob '%s' in 'interpolate', use path.Match syntax, ie. *.webmanifest", glob)
+		}
+	}
+	return nil
+}
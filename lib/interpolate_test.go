@@ -0,0 +1,71 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInterpolateVarsKeepsUnknownPlaceholders(t *testing.T) {
+	vars := map[string]string{"PUBLIC_URL": "/app", "REACT_APP_TITLE": "Hello"}
+
+	got, changed := InterpolateVars([]byte(`<title>%REACT_APP_TITLE%</title><link href="%PUBLIC_URL%/x.css"> 100% %OTHER%`), vars)
+	want := `<title>Hello</title><link href="/app/x.css"> 100% %OTHER%`
+	if string(got) != want || !changed {
+		t.Fatalf("InterpolateVars() = %q, %v, want %q, true", got, changed, want)
+	}
+
+	if _, changed = InterpolateVars([]byte("%OTHER%"), vars); changed {
+		t.Fatal("did not expect unknown placeholder to change content")
+	}
+}
+
+func TestMatchesGlobs(t *testing.T) {
+	tests := []struct {
+		path  string
+		globs []string
+		want  bool
+	}{
+		{path: "manifest.json", globs: []string{"manifest.json"}, want: true},
+		{path: "/icons/site.webmanifest", globs: []string{"*.webmanifest"}, want: true},
+		{path: "icons/manifest.json", globs: []string{"/manifest.json"}, want: false},
+		{path: "icons/manifest.json", globs: []string{"icons/*.json"}, want: true},
+		{path: "robots.txt", globs: []string{"manifest.json"}, want: false},
+		{path: "robots.txt", globs: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesGlobs(tt.path, tt.globs); got != tt.want {
+			t.Fatalf("MatchesGlobs(%q, %v) = %v, want %v", tt.path, tt.globs, got, tt.want)
+		}
+	}
+
+	if err := CheckGlobs([]string{"[a-"}); err == nil {
+		t.Fatal("expected bad glob error")
+	}
+}
+
+func TestCopyDirInterpolatedReplacesVarsInMatchingFiles(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "manifest.json"), []byte(`{"start_url":"%PUBLIC_URL%/"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "logo.txt"), []byte("%PUBLIC_URL%"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyDirInterpolated(dst, src, []string{"*.json"}, map[string]string{"PUBLIC_URL": "/app"}); err != nil {
+		t.Fatalf("CopyDirInterpolated() error = %v", err)
+	}
+
+	for name, want := range map[string]string{"manifest.json": `{"start_url":"/app/"}`, "logo.txt": "%PUBLIC_URL%"} {
+		got, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
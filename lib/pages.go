@@ -0,0 +1,117 @@
+package lib
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Page is one html page of multi page app, it gets its own entry point and html file
+type Page struct {
+	Name  string
+	Entry string
+	// Template is html file the page starts from, empty uses index.html from static or base dir
+	Template string
+	// Output is html file path in output dir
+	Output string
+}
+
+// Route is url path served by the page in watch, "admin/index.html" serves "/admin" and everything under it
+func (page Page) Route() string {
+	route := path.Clean("/" + filepath.ToSlash(page.Output))
+	if path.Base(route) == "index.html" {
+		return path.Dir(route)
+	}
+	return strings.TrimSuffix(route, path.Ext(route))
+}
+
+// Matches checks if request path belongs to the page
+func (page Page) Matches(urlPath string) bool {
+	route := page.Route()
+	if route == "/" {
+		return true
+	}
+	return urlPath == route || strings.HasPrefix(urlPath, route+"/")
+}
+
+// FindPage returns page for request path, page with longest route wins
+func FindPage(pages []Page, urlPath string) (Page, bool) {
+	found := -1
+	for i, page := range pages {
+		if page.Matches(urlPath) && (found == -1 || len(page.Route()) > len(pages[found].Route())) {
+			found = i
+		}
+	}
+	if found == -1 {
+		return Page{}, false
+	}
+	return pages[found], true
+}
+
+// EntryPages returns configured pages, or single index page made from EntryFileName
+func (c Config) EntryPages() []Page {
+	if len(c.Pages) > 0 {
+		return c.Pages
+	}
+	return []Page{{Name: "index", Entry: c.EntryFileName, Output: "index.html"}}
+}
+
+func parsePages(options map[string]any, key string, target *[]Page) error {
+	value, ok := options[key]
+	if !ok {
+		return nil
+	}
+
+	rawMap, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("wrong '%s' key in 'package.json', use object", key)
+	}
+
+	pages := make([]Page, 0, len(rawMap))
+	for name, rawPage := range rawMap {
+		page := Page{Name: name, Output: name + "/index.html"}
+
+		switch v := rawPage.(type) {
+		case string:
+			page.Entry = v
+		case map[string]any:
+			for field, dst := range map[string]*string{"entry": &page.Entry, "template": &page.Template, "output": &page.Output} {
+				if fieldValue, ok := v[field]; ok {
+					s, ok := fieldValue.(string)
+					if !ok {
+						return fmt.Errorf("wrong '%s.%s.%s' key in 'package.json', use string", key, name, field)
+					}
+					*dst = s
+				}
+			}
+		default:
+			return fmt.Errorf("wrong '%s.%s' key in 'package.json', use entry file string or object with 'entry', 'template' and 'output'", key, name)
+		}
+
+		if page.Entry == "" {
+			return fmt.Errorf("wrong '%s.%s' key in 'package.json', 'entry' is required", key, name)
+		}
+		if page.Output == "" || filepath.Ext(page.Output) != ".html" {
+			return fmt.Errorf("wrong '%s.%s.output' key in 'package.json', use html file path", key, name)
+		}
+
+		pages = append(pages, page)
+	}
+
+	slices.SortFunc(pages, func(a, b Page) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for i := 1; i < len(pages); i++ {
+		for _, other := range pages[:i] {
+			if path.Clean(filepath.ToSlash(pages[i].Output)) == path.Clean(filepath.ToSlash(other.Output)) {
+				return fmt.Errorf("wrong '%s' key in 'package.json', pages '%s' and '%s' have same output", key, other.Name, pages[i].Name)
+			}
+		}
+	}
+
+	*target = pages
+	return nil
+}
@@ -0,0 +1,255 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/natrim/nrb/lib"
+)
+
+// HMRUpdateEntry is entry point name for update bundles made with HMRUpdatePlugin
+const HMRUpdateEntry = "nrb-hmr-update"
+
+const hmrRuntimeName = "nrb-hmr-runtime"
+const hmrModuleNamespace = "nrb-hmr"
+const hmrRefNamespace = "nrb-hmr-ref"
+const hmrEmptyNamespace = "nrb-hmr-empty"
+
+// marks our own build.Resolve calls, so onResolve does not loop
+var hmrResolving = &struct{}{}
+
+var hookCallReg = regexp.MustCompile(`\b(use[A-Z][A-Za-z0-9_]*)\s*\(`)
+
+// hmrRuntimeJS keeps registry of loaded modules for update bundles and talks to react-refresh
+const hmrRuntimeJS = `import RefreshRuntime from "react-refresh/runtime";
+RefreshRuntime.injectIntoGlobalHook(window);
+window.$RefreshReg$ = () => {};
+window.$RefreshSig$ = () => (type) => type;
+const modules = {};
+const isBoundary = (m) => {
+  let has = false;
+  for (const k in m) {
+    if (k === "__esModule") continue;
+    has = true;
+    if (!RefreshRuntime.isLikelyComponentType(m[k])) return false;
+  }
+  return has;
+};
+const register = (path, m, local, sig) => {
+  const shared = { __esModule: true };
+  for (const k in m) Object.defineProperty(shared, k, { get: () => m[k], enumerable: true });
+  modules[path] = shared;
+  if (!local) return;
+  for (const k in m) {
+    const v = m[k];
+    if (RefreshRuntime.isLikelyComponentType(v)) {
+      RefreshRuntime.register(v, path + " " + k);
+      RefreshRuntime.setSignature(v, sig, false, () => []);
+    }
+  }
+};
+window.__nrbHmr = {
+  register,
+  module(path) {
+    if (!modules[path]) throw new Error("nrb: module " + path + " is not loaded");
+    return modules[path];
+  },
+  accept(path, m, sig) {
+    if (!modules[path] || !isBoundary(modules[path]) || !isBoundary(m)) return false;
+    register(path, m, true, sig);
+    return true;
+  },
+  async apply(data) {
+    const updates = JSON.parse(data);
+    for (const u of updates) {
+      const url = URL.createObjectURL(new Blob([u.code], { type: "text/javascript" }));
+      try {
+        await import(url);
+      } finally {
+        URL.revokeObjectURL(url);
+      }
+    }
+    RefreshRuntime.performReactRefresh();
+    console.info("[nrb] hot updated", updates.map((u) => u.path).join(", "));
+  },
+};
+`
+
+// HMRPlugin prepares watch bundle for hot module replacement,
+// it injects react-refresh runtime before entries and registers every module imported from sourceDir so update bundles can reuse it
+func HMRPlugin(sourceDir string, entryPoints []string) api.Plugin {
+	sourceDir = lib.RealQuickPath(sourceDir)
+	entryFilters := make([]string, len(entryPoints))
+	for i, entryPoint := range entryPoints {
+		entryFilters[i] = escapeRegExp(lib.RealQuickPath(entryPoint))
+	}
+
+	return api.Plugin{
+		Name: "hmr",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: "^" + escapeRegExp(hmrRuntimeName) + "$"},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					return api.OnResolveResult{Path: hmrRuntimeName, Namespace: hmrRuntimeName}, nil
+				})
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: hmrRuntimeName},
+				func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					contents := hmrRuntimeJS
+					return api.OnLoadResult{Contents: &contents, ResolveDir: sourceDir, Loader: api.LoaderJS}, nil
+				})
+
+			// runtime import goes on first line, so it runs before react-dom and source maps keep lines
+			build.OnLoad(api.OnLoadOptions{Filter: "^(" + strings.Join(entryFilters, "|") + ")$"},
+				func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					source, err := os.ReadFile(args.Path)
+					if err != nil {
+						return api.OnLoadResult{}, err
+					}
+					contents := "import \"" + hmrRuntimeName + "\";" + string(source)
+					return api.OnLoadResult{Contents: &contents, ResolveDir: filepath.Dir(args.Path), Loader: sourceLoader(args.Path)}, nil
+				})
+
+			build.OnResolve(api.OnResolveOptions{Filter: ".*"},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					if !isSourceImport(args, sourceDir) {
+						return api.OnResolveResult{}, nil
+					}
+
+					resolved, ok := resolveShared(build, args)
+					if !ok {
+						return api.OnResolveResult{}, nil
+					}
+
+					return api.OnResolveResult{Path: resolved, Namespace: hmrModuleNamespace}, nil
+				})
+
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: hmrModuleNamespace},
+				func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					local := isInDir(args.Path, sourceDir)
+					sig := ""
+					if local {
+						sig = hookSignature(args.Path)
+					}
+					contents := fmt.Sprintf("import * as m from %q;\nexport * from %q;\nconst n = m;\nexport default n[\"default\"];\nwindow.__nrbHmr.register(%q, m, %t, %q);\n", args.Path, args.Path, args.Path, local, sig)
+					return api.OnLoadResult{Contents: &contents, ResolveDir: filepath.Dir(args.Path), Loader: api.LoaderJS}, nil
+				})
+		},
+	}
+}
+
+// HMRUpdatePlugin bundles only the changed file, its imports are taken from modules registered by HMRPlugin in browser
+func HMRUpdatePlugin(sourceDir, changedFile string) api.Plugin {
+	sourceDir = lib.RealQuickPath(sourceDir)
+	changedFile = lib.RealQuickPath(changedFile)
+
+	return api.Plugin{
+		Name: "hmr-update",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: "^" + escapeRegExp(HMRUpdateEntry) + "$"},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					return api.OnResolveResult{Path: changedFile, Namespace: HMRUpdateEntry}, nil
+				})
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: HMRUpdateEntry},
+				func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					contents := fmt.Sprintf("import * as m from %q;\nif (!window.__nrbHmr.accept(%q, m, %q)) throw new Error(\"nrb: cannot hot update %s\");\n", args.Path, args.Path, hookSignature(args.Path), filepath.Base(args.Path))
+					return api.OnLoadResult{Contents: &contents, ResolveDir: filepath.Dir(args.Path), Loader: api.LoaderJS}, nil
+				})
+
+			build.OnResolve(api.OnResolveOptions{Filter: ".*"},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					if args.Importer != changedFile || !isSourceImport(args, sourceDir) {
+						return api.OnResolveResult{}, nil
+					}
+
+					// styles are swapped by css reload
+					if strings.HasSuffix(args.Path, ".css") && !strings.HasSuffix(args.Path, ".module.css") {
+						return api.OnResolveResult{Path: args.Path, Namespace: hmrEmptyNamespace}, nil
+					}
+
+					resolved, ok := resolveShared(build, args)
+					if !ok {
+						return api.OnResolveResult{}, nil
+					}
+
+					return api.OnResolveResult{Path: resolved, Namespace: hmrRefNamespace}, nil
+				})
+
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: hmrRefNamespace},
+				func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					contents := fmt.Sprintf("module.exports = window.__nrbHmr.module(%q);\n", args.Path)
+					return api.OnLoadResult{Contents: &contents, Loader: api.LoaderJS}, nil
+				})
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: hmrEmptyNamespace},
+				func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					contents := ""
+					return api.OnLoadResult{Contents: &contents, Loader: api.LoaderEmpty}, nil
+				})
+		},
+	}
+}
+
+// isSourceImport checks if import comes from file in sourceDir and is not our own resolve call
+func isSourceImport(args api.OnResolveArgs, sourceDir string) bool {
+	if args.PluginData == hmrResolving || args.Namespace != "file" || args.Path == hmrRuntimeName {
+		return false
+	}
+	if args.Kind != api.ResolveJSImportStatement && args.Kind != api.ResolveJSDynamicImport {
+		return false
+	}
+	return isInDir(args.Importer, sourceDir)
+}
+
+// resolveShared resolves import the normal way, only plain js modules can be shared
+func resolveShared(build api.PluginBuild, args api.OnResolveArgs) (string, bool) {
+	result := build.Resolve(args.Path, api.ResolveOptions{
+		Importer:   args.Importer,
+		ResolveDir: args.ResolveDir,
+		Kind:       args.Kind,
+		Namespace:  args.Namespace,
+		PluginData: hmrResolving,
+		With:       args.With,
+	})
+	if len(result.Errors) > 0 || result.External || result.Namespace != "file" {
+		return "", false
+	}
+	if filepath.Ext(result.Path) == ".css" && !strings.HasSuffix(result.Path, ".module.css") {
+		return "", false
+	}
+	return result.Path, true
+}
+
+// hookSignature is list of hooks called in file, when it changes react-refresh remounts components instead of keeping state
+func hookSignature(path string) string {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	hooks := hookCallReg.FindAllStringSubmatch(string(source), -1)
+	names := make([]string, len(hooks))
+	for i, hook := range hooks {
+		names[i] = hook[1]
+	}
+	return strings.Join(names, ",")
+}
+
+func isInDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !strings.Contains(rel, "node_modules")
+}
+
+func sourceLoader(path string) api.Loader {
+	switch filepath.Ext(path) {
+	case ".tsx":
+		return api.LoaderTSX
+	case ".ts", ".mts", ".cts":
+		return api.LoaderTS
+	default:
+		return api.LoaderJSX
+	}
+}
@@ -4,8 +4,6 @@ import (
 	"regexp"
 )
 
-var escapeReg = regexp.MustCompile(`[.*+?^${}()|[\]\\]`)
-
 func escapeRegExp(str string) string {
-	return escapeReg.ReplaceAllString(str, "\\$&")
+	return regexp.QuoteMeta(str)
 }
@@ -0,0 +1,204 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ProxyRule is a dev server proxy route, requests with path starting with Path get piped to Target
+type ProxyRule struct {
+	Path         string
+	Target       string
+	PathRewrite  map[string]string
+	Headers      map[string]string
+	ChangeOrigin bool
+	// Fallback rule is the CRA "proxy" field, it only gets requests nothing else can serve
+	Fallback bool
+}
+
+// Matches checks if request path belongs to the rule
+func (rule ProxyRule) Matches(path string) bool {
+	if rule.Fallback {
+		return false
+	}
+	prefix := strings.TrimSuffix(rule.Path, "/")
+	if prefix == "" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// RewritePath applies PathRewrite regexps in stable order to request path
+func (rule ProxyRule) RewritePath(path string) string {
+	if len(rule.PathRewrite) == 0 {
+		return path
+	}
+
+	patterns := make([]string, 0, len(rule.PathRewrite))
+	for pattern := range rule.PathRewrite {
+		patterns = append(patterns, pattern)
+	}
+	slices.Sort(patterns)
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		path = re.ReplaceAllString(path, rule.PathRewrite[pattern])
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return path
+}
+
+// TargetURL returns the backend url for request path and query
+func (rule ProxyRule) TargetURL(path, rawQuery string) (*url.URL, error) {
+	target, err := url.Parse(rule.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	out := *target
+	out.Path = strings.TrimSuffix(target.Path, "/") + rule.RewritePath(path)
+	out.RawPath = ""
+	if target.RawQuery == "" || rawQuery == "" {
+		out.RawQuery = target.RawQuery + rawQuery
+	} else {
+		out.RawQuery = target.RawQuery + "&" + rawQuery
+	}
+
+	return &out, nil
+}
+
+// SortProxyRules orders rules so the longest path wins and fallback rules go last
+func SortProxyRules(rules []ProxyRule) {
+	slices.SortStableFunc(rules, func(a, b ProxyRule) int {
+		if a.Fallback != b.Fallback {
+			if a.Fallback {
+				return 1
+			}
+			return -1
+		}
+		return len(b.Path) - len(a.Path)
+	})
+}
+
+// ValidateProxyTarget checks that target is absolute http(s) url
+func ValidateProxyTarget(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid proxy target %q, use http(s)://host[:port]", target)
+	}
+	return nil
+}
+
+// ParseProxyFlags makes proxy rules from 'path:target' pairs
+func ParseProxyFlags(flags MapFlags) ([]ProxyRule, error) {
+	rules := make([]ProxyRule, 0, len(flags))
+	for path, target := range flags {
+		if err := ValidateProxyTarget(target); err != nil {
+			return nil, err
+		}
+		rules = append(rules, ProxyRule{Path: "/" + strings.TrimPrefix(path, "/"), Target: target})
+	}
+	SortProxyRules(rules)
+	return rules, nil
+}
+
+func parseProxy(options map[string]any, key string, target *[]ProxyRule) error {
+	value, ok := options[key]
+	if !ok {
+		return nil
+	}
+
+	// "proxy": "http://localhost:8080" behaves like CRA
+	if s, ok := value.(string); ok {
+		if err := ValidateProxyTarget(s); err != nil {
+			return fmt.Errorf("wrong '%s' key in 'package.json', %w", key, err)
+		}
+		*target = []ProxyRule{{Path: "/", Target: s, Fallback: true}}
+		return nil
+	}
+
+	rawMap, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("wrong '%s' key in 'package.json', use string or object", key)
+	}
+
+	rules := make([]ProxyRule, 0, len(rawMap))
+	for path, ruleValue := range rawMap {
+		rule := ProxyRule{Path: "/" + strings.TrimPrefix(path, "/")}
+
+		switch r := ruleValue.(type) {
+		case string:
+			rule.Target = r
+		case map[string]any:
+			targetValue, ok := r["target"].(string)
+			if !ok {
+				return fmt.Errorf("wrong '%s.%s.target' key in 'package.json', use string", key, path)
+			}
+			rule.Target = targetValue
+
+			if err := parseOptionalProxyMap(r, "pathRewrite", &rule.PathRewrite); err != nil {
+				return fmt.Errorf("wrong '%s.%s.pathRewrite' key in 'package.json', %w", key, path, err)
+			}
+			for pattern := range rule.PathRewrite {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("wrong '%s.%s.pathRewrite' regexp %q in 'package.json', %w", key, path, pattern, err)
+				}
+			}
+			if err := parseOptionalProxyMap(r, "headers", &rule.Headers); err != nil {
+				return fmt.Errorf("wrong '%s.%s.headers' key in 'package.json', %w", key, path, err)
+			}
+			if changeOrigin, ok := r["changeOrigin"]; ok {
+				b, ok := changeOrigin.(bool)
+				if !ok {
+					return fmt.Errorf("wrong '%s.%s.changeOrigin' key in 'package.json', use boolean: true|false", key, path)
+				}
+				rule.ChangeOrigin = b
+			}
+		default:
+			return fmt.Errorf("wrong '%s.%s' key in 'package.json', use string or object", key, path)
+		}
+
+		if err := ValidateProxyTarget(rule.Target); err != nil {
+			return fmt.Errorf("wrong '%s.%s' key in 'package.json', %w", key, path, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	SortProxyRules(rules)
+	*target = rules
+	return nil
+}
+
+func parseOptionalProxyMap(options map[string]any, key string, target *map[string]string) error {
+	value, ok := options[key]
+	if !ok {
+		return nil
+	}
+
+	rawMap, ok := value.(map[string]any)
+	if !ok {
+		return errors.New("use object")
+	}
+
+	result := make(map[string]string, len(rawMap))
+	for name, mappedValue := range rawMap {
+		result[name] = fmt.Sprintf("%v", mappedValue)
+	}
+	*target = result
+	return nil
+}
@@ -0,0 +1,143 @@
+package lib
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// esbuild content hash in file name, ie. 'chunks/settings-ABCD2345.js'
+var outputHash = regexp.MustCompile(`-[A-Z2-7]{8}(\.[^/]*)?$`)
+
+// SizeSnapshot is compact stats of build files, saved to compare next build with
+type SizeSnapshot struct {
+	Time int64 `json:"time"`
+	// Files are by SizeKey
+	Files map[string]BundleFile `json:"files"`
+}
+
+// BrotliSize is size of brotli compressed content with default quality
+func BrotliSize(content []byte) int64 {
+	var b bytes.Buffer
+	w := brotli.NewWriterLevel(&b, brotli.DefaultCompression)
+	_, _ = w.Write(content)
+	_ = w.Close()
+	return int64(b.Len())
+}
+
+// SizeKey is file path without content hash, so same chunk matches between builds
+func SizeKey(path string) string {
+	return outputHash.ReplaceAllString(path, "$1")
+}
+
+// NewSizeSnapshot makes snapshot of files, files with same key without hash (ie. shared 'chunk-HASH.js' chunks) get key
+// by their inputs, so the chunk matches next build even with changed content, without inputs they are kept by full path
+func NewSizeSnapshot(files []BundleFile, time int64) SizeSnapshot {
+	keys := make(map[string]int)
+	for _, file := range files {
+		keys[SizeKey(file.Path)]++
+	}
+	inputKeys := make(map[string]int)
+	for _, file := range files {
+		if keys[SizeKey(file.Path)] > 1 && len(file.Inputs) > 0 {
+			inputKeys[inputsKey(file)]++
+		}
+	}
+
+	snapshot := SizeSnapshot{Time: time, Files: make(map[string]BundleFile, len(files))}
+	for _, file := range files {
+		key := SizeKey(file.Path)
+		if keys[key] > 1 {
+			key = inputsKey(file)
+			if inputKeys[key] != 1 {
+				key = file.Path
+			}
+		}
+		snapshot.Files[key] = file
+	}
+	return snapshot
+}
+
+// inputsKey is SizeKey with hash of sorted file inputs, ie. 'chunks/chunk.js#1a2b3c4d'
+func inputsKey(file BundleFile) string {
+	inputs := slices.Sorted(slices.Values(file.Inputs))
+	sum := sha256.Sum256([]byte(strings.Join(inputs, "\n")))
+	return SizeKey(file.Path) + "#" + hex.EncodeToString(sum[:4])
+}
+
+// SizeReport makes table rows of files sorted by size, with delta of raw size against previous snapshot if there is one,
+// files removed since previous snapshot go last
+func SizeReport(current, previous *SizeSnapshot) [][]string {
+	keys := make([]string, 0, len(current.Files))
+	var total BundleFile
+	for key, file := range current.Files {
+		keys = append(keys, key)
+		total.Raw += file.Raw
+		total.Gzip += file.Gzip
+		total.Brotli += file.Brotli
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if c := cmp.Compare(current.Files[b].Raw, current.Files[a].Raw); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	header := []string{"FILE", "RAW", "GZIP", "BROTLI"}
+	if previous != nil {
+		header = append(header, "DELTA")
+	}
+	rows := [][]string{header}
+
+	var previousTotal int64
+	for _, key := range keys {
+		file := current.Files[key]
+		row := []string{file.Path, FormatSize(file.Raw), FormatSize(file.Gzip), FormatSize(file.Brotli)}
+		if previous != nil {
+			if before, ok := previous.Files[key]; ok {
+				row = append(row, FormatDelta(file.Raw-before.Raw))
+			} else {
+				row = append(row, "new")
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if previous != nil {
+		var removed []string
+		for key, file := range previous.Files {
+			previousTotal += file.Raw
+			if _, ok := current.Files[key]; !ok {
+				removed = append(removed, key)
+			}
+		}
+		slices.Sort(removed)
+		for _, key := range removed {
+			rows = append(rows, []string{previous.Files[key].Path, "", "", "", "removed"})
+		}
+	}
+
+	totalRow := []string{"total", FormatSize(total.Raw), FormatSize(total.Gzip), FormatSize(total.Brotli)}
+	if previous != nil {
+		totalRow = append(totalRow, FormatDelta(total.Raw-previousTotal))
+	}
+	return append(rows, totalRow)
+}
+
+// FormatDelta formats size change with sign, empty if nothing changed
+func FormatDelta(delta int64) string {
+	switch {
+	case delta > 0:
+		return "+" + FormatSize(delta)
+	case delta < 0:
+		return "-" + FormatSize(-delta)
+	default:
+		return ""
+	}
+}
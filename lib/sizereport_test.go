@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestNewSizeSnapshotKeysFilesWithoutHash(t *testing.T) {
+	snapshot := NewSizeSnapshot([]BundleFile{
+		{Path: "assets/index-ABCD2345.js", Raw: 10},
+		{Path: "assets/index-ABCD2345.css", Raw: 5},
+		{Path: "assets/chunk-AAAA2222.js", Raw: 3},
+		{Path: "assets/chunk-BBBB3333.js", Raw: 4},
+		{Path: "media/logo.png", Raw: 2},
+	}, 1)
+
+	keys := slices.Sorted(maps.Keys(snapshot.Files))
+	want := []string{"assets/chunk-AAAA2222.js", "assets/chunk-BBBB3333.js", "assets/index.css", "assets/index.js", "media/logo.png"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("snapshot keys = %v, want %v", keys, want)
+	}
+}
+
+func TestSizeReportMatchesSharedChunksByInputs(t *testing.T) {
+	previous := NewSizeSnapshot([]BundleFile{
+		{Path: "chunks/chunk-AAAA2222.js", Raw: 1000, Inputs: []string{"src/api.ts", "src/utils.ts"}},
+		{Path: "chunks/chunk-BBBB3333.js", Raw: 2000, Inputs: []string{"node_modules/react/index.js"}},
+	}, 1)
+	// content changed, so hashes changed too
+	current := NewSizeSnapshot([]BundleFile{
+		{Path: "chunks/chunk-CCCC4444.js", Raw: 2100, Inputs: []string{"node_modules/react/index.js"}},
+		{Path: "chunks/chunk-DDDD5555.js", Raw: 900, Inputs: []string{"src/utils.ts", "src/api.ts"}},
+	}, 2)
+
+	want := [][]string{
+		{"FILE", "RAW", "GZIP", "BROTLI", "DELTA"},
+		{"chunks/chunk-CCCC4444.js", "2.05 kB", "0 B", "0 B", "+100 B"},
+		{"chunks/chunk-DDDD5555.js", "900 B", "0 B", "0 B", "-100 B"},
+		{"total", "2.93 kB", "0 B", "0 B", ""},
+	}
+	if got := SizeReport(&current, &previous); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SizeReport() = %q, want %q", got, want)
+	}
+}
+
+func TestSizeReportShowsDeltaAgainstPreviousBuild(t *testing.T) {
+	previous := NewSizeSnapshot([]BundleFile{
+		{Path: "assets/index-AAAA2222.js", Raw: 2048},
+		{Path: "assets/old-AAAA2222.js", Raw: 100},
+		{Path: "assets/same-AAAA2222.js", Raw: 10},
+	}, 1)
+	current := NewSizeSnapshot([]BundleFile{
+		{Path: "assets/index-BBBB3333.js", Raw: 3072, Gzip: 1024, Brotli: 900},
+		{Path: "assets/settings-BBBB3333.js", Raw: 500, Gzip: 200, Brotli: 150},
+		{Path: "assets/same-AAAA2222.js", Raw: 10, Gzip: 5, Brotli: 4},
+	}, 2)
+
+	want := [][]string{
+		{"FILE", "RAW", "GZIP", "BROTLI", "DELTA"},
+		{"assets/index-BBBB3333.js", "3.00 kB", "1.00 kB", "900 B", "+1.00 kB"},
+		{"assets/settings-BBBB3333.js", "500 B", "200 B", "150 B", "new"},
+		{"assets/same-AAAA2222.js", "10 B", "5 B", "4 B", ""},
+		{"assets/old-AAAA2222.js", "", "", "", "removed"},
+		{"total", "3.50 kB", "1.20 kB", "1.03 kB", "+1.39 kB"},
+	}
+	if got := SizeReport(&current, &previous); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SizeReport() = %q, want %q", got, want)
+	}
+
+	if got := SizeReport(&current, nil); len(got[0]) != 4 || len(got) != 5 {
+		t.Fatalf("SizeReport() without previous = %q, want no delta column", got)
+	}
+}
@@ -6,13 +6,22 @@ import (
 	"time"
 )
 
+// Event is a named server sent event, clients listen to it with addEventListener
+type Event struct {
+	Name string
+	Data []byte
+}
+
 // A Broker holds open client connections,
-// listens for incoming events on its Notifier channel
+// listens for incoming events on its Notifier and Events channels
 // and broadcast event data to all registered connections
 type Broker struct {
 	// Events are pushed to this channel by the main events-gathering routine
 	Notifier chan []byte
 
+	// Named events are pushed to this channel by the main events-gathering routine
+	Events chan Event
+
 	// New client connections
 	newClients chan chan []byte
 
@@ -27,6 +36,7 @@ func NewStreamServer() (broker *Broker) {
 	// Instantiate a broker
 	broker = &Broker{
 		Notifier:       make(chan []byte, 1),
+		Events:         make(chan Event, 1),
 		newClients:     make(chan chan []byte),
 		closingClients: make(chan chan []byte),
 		clients:        make(map[chan []byte]bool),
@@ -99,8 +109,8 @@ func (broker *Broker) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	for {
 		// Write to the ResponseWriter
-		// Server Sent Events compatible
-		_, _ = fmt.Fprintf(rw, "data: %s\n\n", <-messageChan)
+		// Server Sent Events compatible, messages are already formatted by listen
+		_, _ = rw.Write(<-messageChan)
 
 		// Flush the data immediately instead of buffering it for later.
 		flusher.Flush()
@@ -123,9 +133,16 @@ func (broker *Broker) listen() {
 		case event := <-broker.Notifier:
 			// We got a new event from the outside!
 			// Send event to all connected clients
-			for clientMessageChan := range broker.clients {
-				clientMessageChan <- event
-			}
+			broker.broadcast(fmt.Appendf(nil, "data: %s\n\n", event))
+		case event := <-broker.Events:
+			// We got a new named event from the outside!
+			broker.broadcast(fmt.Appendf(nil, "event: %s\ndata: %s\n\n", event.Name, event.Data))
 		}
 	}
 }
+
+func (broker *Broker) broadcast(message []byte) {
+	for clientMessageChan := range broker.clients {
+		clientMessageChan <- message
+	}
+}
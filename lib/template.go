@@ -0,0 +1,39 @@
+package lib
+
+import (
+	"bytes"
+	"text/template"
+)
+
+// IndexData is data model of index.html rendered as go template
+type IndexData struct {
+	Config  Config
+	Mode    string
+	Version string
+	// Env are env variables with EnvPrefix
+	Env    map[string]string
+	Chunks []IndexChunk
+}
+
+// IndexChunk is one built js/css output file
+type IndexChunk struct {
+	// Path is url of the file including public url
+	Path string
+	// EntryPoint is source file of entry chunk, empty for other chunks
+	EntryPoint string
+	Bytes      int
+}
+
+// RenderIndexTemplate renders index.html content as go text/template, values are not html escaped, use {{html .Env.NAME}} for that
+func RenderIndexTemplate(name string, indexFile []byte, data IndexData) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(string(indexFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err = tmpl.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -2,7 +2,9 @@ package lib
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"text/tabwriter"
 )
 
 const (
@@ -25,6 +27,14 @@ var ITEM = "-"
 var DASH = "–"
 var WARN = "⚠"
 
+// out is where non error output goes
+var out io.Writer = os.Stdout
+
+// UseStderr sends all output to stderr, so stdout is free for data, ie. json
+func UseStderr() {
+	out = os.Stderr
+}
+
 func UseColor(use bool) {
 	if use {
 		ERR = Red(ERR)
@@ -38,11 +48,11 @@ func UseColor(use bool) {
 }
 
 func Print(a ...any) {
-	_, _ = fmt.Fprintln(os.Stdout, a...)
+	_, _ = fmt.Fprintln(out, a...)
 }
 
 func Printf(format string, a ...any) {
-	_, _ = fmt.Fprintf(os.Stdout, format, a...)
+	_, _ = fmt.Fprintf(out, format, a...)
 }
 
 func Printe(a ...any) {
@@ -62,43 +72,58 @@ func PrintErrorf(format string, a ...any) {
 }
 
 func PrintInfo(a ...any) {
-	_, _ = fmt.Fprintln(os.Stdout, append([]any{INFO}, a...)...)
+	_, _ = fmt.Fprintln(out, append([]any{INFO}, a...)...)
 }
 
 func PrintInfof(format string, a ...any) {
-	_, _ = fmt.Fprintf(os.Stdout, "%s "+format, append([]any{INFO}, a...)...)
+	_, _ = fmt.Fprintf(out, "%s "+format, append([]any{INFO}, a...)...)
 }
 
 func PrintOk(a ...any) {
-	_, _ = fmt.Fprintln(os.Stdout, append([]any{OK}, a...)...)
+	_, _ = fmt.Fprintln(out, append([]any{OK}, a...)...)
 }
 
 func PrintOkf(format string, a ...any) {
-	_, _ = fmt.Fprintf(os.Stdout, "%s "+format, append([]any{OK}, a...)...)
+	_, _ = fmt.Fprintf(out, "%s "+format, append([]any{OK}, a...)...)
 }
 
 func PrintWarn(a ...any) {
-	_, _ = fmt.Fprintln(os.Stdout, append([]any{WARN}, a...)...)
+	_, _ = fmt.Fprintln(out, append([]any{WARN}, a...)...)
 }
 
 func PrintWarnf(format string, a ...any) {
-	_, _ = fmt.Fprintf(os.Stdout, "%s "+format, append([]any{WARN}, a...)...)
+	_, _ = fmt.Fprintf(out, "%s "+format, append([]any{WARN}, a...)...)
 }
 
 func PrintItem(a ...any) {
-	_, _ = fmt.Fprintln(os.Stdout, append([]any{ITEM}, a...)...)
+	_, _ = fmt.Fprintln(out, append([]any{ITEM}, a...)...)
 }
 
 func PrintItemf(format string, a ...any) {
-	_, _ = fmt.Fprintf(os.Stdout, "%s "+format, append([]any{ITEM}, a...)...)
+	_, _ = fmt.Fprintf(out, "%s "+format, append([]any{ITEM}, a...)...)
 }
 
 func PrintReload(a ...any) {
-	_, _ = fmt.Fprintln(os.Stdout, append([]any{RELOAD}, a...)...)
+	_, _ = fmt.Fprintln(out, append([]any{RELOAD}, a...)...)
 }
 
 func PrintReloadf(format string, a ...any) {
-	_, _ = fmt.Fprintf(os.Stdout, "%s "+format, append([]any{RELOAD}, a...)...)
+	_, _ = fmt.Fprintf(out, "%s "+format, append([]any{RELOAD}, a...)...)
+}
+
+// PrintTable prints rows aligned in columns, first row is header
+func PrintTable(rows [][]string) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		for i, cell := range row {
+			if i > 0 {
+				_, _ = fmt.Fprint(w, "\t")
+			}
+			_, _ = fmt.Fprint(w, cell)
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	_ = w.Flush()
 }
 
 func Black(s string) string {